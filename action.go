@@ -2,8 +2,11 @@ package flagx
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"reflect"
 	"strings"
+	"time"
 
 	"github.com/henrylee2cn/goutil/status"
 )
@@ -38,13 +41,42 @@ type (
 	// NOTE:
 	//  If need to return an error, use *Context.ThrowStatus or *Context.CheckStatus
 	FilterFunc func(c *Context, next ActionFunc)
+	// ActionE action that returns an error, which is set by WrapActionE
+	// NOTE:
+	//  the error is converted into a status, see App.SetErrorCode
+	ActionE interface {
+		// Execute executes action.
+		Execute(*Context) error
+	}
+	// ActionFuncE action function that returns an error
+	ActionFuncE func(*Context) error
+	// FilterE filter that returns an error, which is added by WrapFilterE
+	// NOTE:
+	//  the error is converted into a status, see App.SetErrorCode
+	FilterE interface {
+		Filter(c *Context, next ActionFunc) error
+	}
+	// FilterFuncE filter function that returns an error
+	FilterFuncE func(c *Context, next ActionFunc) error
 	// Context context of an action execution
 	Context struct {
 		context.Context
-		args      []string
-		cmdPath   []string
-		cmd       *Command
-		execScope Scope
+		args       []string
+		cmdPath    []string
+		cmd        *Command
+		execScope  Scope
+		flagOutput io.Writer
+		filters    []interface{}
+		action     interface{}
+		nextArgs   []string                   // the arguments left by the action
+		dryRun     bool                       // only resolves the command without side effects
+		parent     *Context                   // the context that executes the command by Context.Exec
+		skipped    map[*Command]bool          // the commands whose filters are skipped
+		applied    map[*Command][]interface{} // the filters created for the commands
+		timeout    time.Duration              // the value of the global timeout option
+		stdio      execIO
+		lookupEnv  LookupEnvFunc
+		environ    []string // the environment passed to the plugins, nil means os.Environ
 	}
 )
 
@@ -52,19 +84,20 @@ type (
 	contextKey    int8
 	actionFactory struct {
 		elemType reflect.Type
+		proto    reflect.Value // the prototype struct
+		mode     CopyMode
 	}
-	factory      actionFactory
 	actionObject struct {
-		cmd           *Command
-		flagSet       *FlagSet
-		options       map[string]*Flag
-		actionFactory ActionCopier
-		actionFunc    ActionFunc
+		cmd        *Command
+		flagSet    *FlagSet
+		options    map[string]*Flag
+		newObject  func() interface{} // creates the struct action
+		actionFunc ActionFunc
 	}
 	filterObject struct {
 		flagSet    *FlagSet
 		options    map[string]*Flag
-		factory    FilterCopier
+		newObject  func() interface{} // creates the struct filter
 		filterFunc FilterFunc
 	}
 )
@@ -79,12 +112,14 @@ func (fn FilterFunc) Filter(c *Context, next ActionFunc) {
 	fn(c, next)
 }
 
-func (h *actionFactory) DeepCopy() Action {
-	return reflect.New(h.elemType).Interface().(Action)
+// Execute implements ActionE interface.
+func (fn ActionFuncE) Execute(c *Context) error {
+	return fn(c)
 }
 
-func (f *factory) DeepCopy() Filter {
-	return reflect.New(f.elemType).Interface().(Filter)
+// Filter implements FilterE interface.
+func (fn FilterFuncE) Filter(c *Context, next ActionFunc) error {
+	return fn(c, next)
 }
 
 // Args returns the command arguments.
@@ -122,6 +157,114 @@ func (c *Context) UsageText() string {
 	return c.cmd.UsageText(c.execScope)
 }
 
+// Filters returns the filters of the execution from the outermost to the innermost,
+// including the parsed struct filter objects, each of which is Filter or FilterE.
+func (c *Context) Filters() []interface{} {
+	return c.filters
+}
+
+// Action returns the action of the execution, which is the parsed struct action object,
+// or the Action or ActionE function.
+// NOTE:
+//  returns nil if the action is not found.
+func (c *Context) Action() interface{} {
+	return c.action
+}
+
+// Filter sets the innermost filter object of the type to which @target points,
+// and reports whether it is found.
+// NOTE:
+//  @target can point to a pointer of the struct filter type, such as **Filter1,
+//  or to the struct itself, such as *Filter1, which receives a copy;
+//  panic when @target is not a non-nil pointer.
+func (c *Context) Filter(target interface{}) bool {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic(fmt.Errorf("flagx: want non-nil pointer target, but got %T", target))
+	}
+	elem := v.Elem()
+	for i := len(c.filters) - 1; i >= 0; i-- {
+		fv := reflect.ValueOf(c.filters[i])
+		if fv.Type().AssignableTo(elem.Type()) {
+			elem.Set(fv)
+			return true
+		}
+		if fv.Kind() == reflect.Ptr && fv.Type().Elem() == elem.Type() {
+			elem.Set(fv.Elem())
+			return true
+		}
+	}
+	return false
+}
+
+// SetValue stores the value by the key in the context,
+// so that it is visible to the later filters and the action of the execution.
+// NOTE:
+//  it replaces the embedded context.Context in place, and is not safe for concurrent use;
+//  the key should not be of a built-in type, see context.WithValue.
+func (c *Context) SetValue(key, val interface{}) {
+	c.Context = context.WithValue(c.Context, key, val)
+}
+
+// MustValue returns the value by the key.
+// NOTE:
+//  panic when the value does not exist
+func (c *Context) MustValue(key interface{}) interface{} {
+	val := c.Value(key)
+	if val == nil {
+		panic(fmt.Errorf("flagx: no context value for the key %v", key))
+	}
+	return val
+}
+
+// GetString returns the string value by the key, and reports whether it exists.
+func (c *Context) GetString(key interface{}) (val string, ok bool) {
+	val, ok = c.Value(key).(string)
+	return
+}
+
+// GetBool returns the bool value by the key, and reports whether it exists.
+func (c *Context) GetBool(key interface{}) (val bool, ok bool) {
+	val, ok = c.Value(key).(bool)
+	return
+}
+
+// GetInt returns the int value by the key, and reports whether it exists.
+func (c *Context) GetInt(key interface{}) (val int, ok bool) {
+	val, ok = c.Value(key).(int)
+	return
+}
+
+// GetInt64 returns the int64 value by the key, and reports whether it exists.
+func (c *Context) GetInt64(key interface{}) (val int64, ok bool) {
+	val, ok = c.Value(key).(int64)
+	return
+}
+
+// GetFloat64 returns the float64 value by the key, and reports whether it exists.
+func (c *Context) GetFloat64(key interface{}) (val float64, ok bool) {
+	val, ok = c.Value(key).(float64)
+	return
+}
+
+// GetDuration returns the time.Duration value by the key, and reports whether it exists.
+func (c *Context) GetDuration(key interface{}) (val time.Duration, ok bool) {
+	val, ok = c.Value(key).(time.Duration)
+	return
+}
+
+// newFlagSet returns a flag set used to parse the arguments of the execution.
+func (c *Context) newFlagSet(name string, errorHandling ErrorHandling) *FlagSet {
+	f := NewFlagSet(name, errorHandling)
+	if c.flagOutput != nil {
+		f.SetOutput(c.flagOutput)
+	} else {
+		f.SetOutput(c.Stderr())
+	}
+	f.SetEnvLookup(c.LookupEnv)
+	return f
+}
+
 // ThrowStatus creates a status with stack, and panic.
 func (c *Context) ThrowStatus(code int32, msg string, cause ...interface{}) {
 	panic(status.New(code, msg, cause...).TagStack(1))
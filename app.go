@@ -3,14 +3,15 @@ package flagx
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"sync"
 	"text/template"
 	"time"
 
-	"github.com/henrylee2cn/goutil"
 	"github.com/henrylee2cn/goutil/status"
 )
 
@@ -22,11 +23,41 @@ type (
 		appName                 string
 		version                 string
 		compiled                time.Time
+		buildInfo               BuildInfo
+		versionTemplate         *template.Template
 		authors                 []Author
 		copyright               string
 		notFound                ActionFunc
+		before                  BeforeFunc
+		onError                 ErrorFunc
+		after                   AfterFunc
+		prefixMatching          bool
+		plugins                 bool
+		pluginDirs              []string          // the directories of the plugins, empty means $PATH
+		pluginPaths             map[string]string // the plugin executables listed in the usage by name
+		helpOutput              io.Writer
+		warningOutput           io.Writer
+		stdio                   execIO
+		lookupEnv               LookupEnvFunc
+		warned                  map[string]bool // the deprecations warned
+		warnedLock              sync.Mutex
+		exitCodes               map[int32]int
+		renderer                StatusRenderer
 		usageTemplate           *template.Template
+		usageFormat             UsageFormat
+		usageWidth              int
+		groupOrder              []string
 		validator               ValidateFunc
+		copyMode                CopyMode
+		errorCode               ErrorCodeFunc
+		panicPolicy             PanicPolicy
+		panicHandler            PanicFunc
+		signals                 bool
+		signalGrace             time.Duration
+		timeoutOption           bool
+		timeoutGrace            time.Duration
+		providers               map[reflect.Type]*provider
+		namedProviders          map[string]*provider
 		usageText               string
 		execScopeUsageTexts     map[Scope]string
 		execScopeUsageTextsLock sync.RWMutex
@@ -58,10 +89,20 @@ const (
 	StatusParseFailed    int32 = 3
 	StatusValidateFailed int32 = 4
 	StatusMismatchScope  int32 = 5
+	StatusPluginFailed   int32 = 6
+	StatusInjectFailed   int32 = 7
+	StatusActionFailed   int32 = 8
+	StatusPanic          int32 = 9
+	StatusInterrupted    int32 = 10
+	StatusTimeout        int32 = 11
+	StatusRecursion      int32 = 12
 )
 
 const (
 	currCmdName contextKey = iota
+	ioContextKey
+	envContextKey
+	environContextKey
 )
 
 var (
@@ -108,7 +149,11 @@ var (
 func NewApp() *App {
 	a := new(App)
 	a.Command = newCommand(a, "", "")
+	a.exitCodes = defaultExitCodes()
+	a.timeoutGrace = defaultTimeoutGrace
+	a.buildInfo = ReadBuildInfo()
 	a.SetUsageTemplate(defaultAppUsageTemplate)
+	a.SetVersionTemplate(defaultVersionTemplate)
 	a.SetCmdName("")
 	a.SetName("")
 	a.SetVersion("")
@@ -134,6 +179,7 @@ func (a *App) SetCmdName(cmdName string) {
 		cmdName = filepath.Base(os.Args[0])
 	}
 	a.cmdName = strings.TrimLeft(cmdName, "-")
+	a.discoverPluginsLocked()
 	a.updateUsageLocked()
 }
 
@@ -179,9 +225,15 @@ func (a *App) Version() string {
 }
 
 // SetVersion sets the version of the application.
+// NOTE:
+//  if version is empty, the module version of the build information is used,
+//  and defaults to 0.0.1
 func (a *App) SetVersion(version string) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
+	if version == "" {
+		version = a.buildInfo.Version
+	}
 	version = strings.TrimPrefix(version, "v")
 	version = strings.TrimPrefix(version, "V")
 	if version == "" {
@@ -199,9 +251,15 @@ func (a *App) Compiled() time.Time {
 }
 
 // SetCompiled sets the compilation date.
+// NOTE:
+//  if date is zero, the vcs time of the build information is used,
+//  and defaults to the modification time of the binary
 func (a *App) SetCompiled(date time.Time) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
+	if date.IsZero() {
+		date = a.buildInfo.Time
+	}
 	if date.IsZero() {
 		info, err := os.Stat(os.Args[0])
 		if err != nil {
@@ -214,6 +272,13 @@ func (a *App) SetCompiled(date time.Time) {
 	a.updateUsageLocked()
 }
 
+// BuildInfo returns the build information embedded in the binary.
+func (a *App) BuildInfo() BuildInfo {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.buildInfo
+}
+
 // Authors returns the list of all authors who contributed.
 func (a *App) Authors() []Author {
 	a.lock.RLock()
@@ -251,6 +316,71 @@ func (a *App) SetNotFound(fn ActionFunc) {
 	a.notFound = fn
 }
 
+// SetPrefixMatching sets whether a subcommand can be executed by
+// the unambiguous prefix of its name, e.g. `app dep st` for `app deploy status`.
+// NOTE:
+//  defaults to false;
+//  the exact names and aliases always win over the prefixes.
+func (a *App) SetPrefixMatching(enable bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.prefixMatching = enable
+}
+
+// SetHelpOutput sets the destination for the usage requested by
+// `-h`/`--help` or the `help` command, and the version requested by
+// `--version` or the `version` command.
+// NOTE:
+//  if w is nil, the standard output of the execution is used, see SetIO.
+func (a *App) SetHelpOutput(w io.Writer) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.helpOutput = w
+}
+
+// SetWarningOutput sets the destination for the warnings,
+// such as the use of deprecated commands and flags.
+// NOTE:
+//  if w is nil, the standard error of the execution is used, see SetIO.
+func (a *App) SetWarningOutput(w io.Writer) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.warningOutput = w
+}
+
+// warnOnce prints the warning only the first time the key is seen.
+func (a *App) warnOnce(ctxObj *Context, key string, format string, args ...interface{}) {
+	if ctxObj.dryRun {
+		return
+	}
+	a.warnedLock.Lock()
+	if a.warned[key] {
+		a.warnedLock.Unlock()
+		return
+	}
+	if a.warned == nil {
+		a.warned = make(map[string]bool)
+	}
+	a.warned[key] = true
+	a.warnedLock.Unlock()
+	a.lock.RLock()
+	w := a.warningOutput
+	a.lock.RUnlock()
+	if w == nil {
+		w = ctxObj.Stderr()
+	}
+	fmt.Fprintf(w, "warning: "+format+"\n", args...)
+}
+
+func (a *App) helpWriter(ctxObj *Context) io.Writer {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.helpOutput == nil {
+		return ctxObj.Stdout()
+	}
+	return a.helpOutput
+}
+
 // SetValidator sets parameter validator for struct action and struct filter.
 func (a *App) SetValidator(fn ValidateFunc) {
 	a.lock.Lock()
@@ -259,10 +389,14 @@ func (a *App) SetValidator(fn ValidateFunc) {
 }
 
 // SetUsageTemplate sets usage template.
+// NOTE:
+//  the template data contains the rendered `.Usage` text and the structured
+//  `.Command` *CommandUsage, see UsageFuncMap for the template functions.
 func (a *App) SetUsageTemplate(tmpl *template.Template) {
 	a.lock.Lock()
 	defer a.lock.Unlock()
 	a.usageTemplate = tmpl
+	a.updateUsageLocked()
 }
 
 // SetScopeMatcher sets the scope matching function.
@@ -299,6 +433,7 @@ func (a *App) UsageText(execScope ...Scope) string {
 
 // defaultAppUsageTemplate is the text template for the Default help topic.
 var defaultAppUsageTemplate = template.Must(template.New("appUsage").
+	Funcs(UsageFuncMap()).
 	Parse(`{{if .AppName}}{{.AppName}}{{else}}{{.CmdName}}{{end}}{{if .Version}} - v{{.Version}}{{end}}{{if .Description}}
 
 {{.Description}}{{end}}
@@ -316,41 +451,29 @@ COPYRIGHT:
 
 func (a *App) updateUsageLocked() {
 	a.Command.updateUsageLocked()
-	text := goutil.Indent(a.Command.UsageText(), "  ")
-	data := map[string]interface{}{
-		"AppName":     a.appName,
-		"CmdName":     a.cmdName,
-		"Version":     a.version,
-		"Description": a.description,
-		"Authors":     a.authors,
-		"Usage":       text,
-		"Copyright":   a.copyright,
-	}
-	var buf bytes.Buffer
-	err := a.usageTemplate.Execute(&buf, data)
-	if err != nil {
-		panic(err)
-	}
-	s := buf.String()
-	for {
-		a.usageText = strings.Replace(s, "\n\n\n", "\n\n", -1)
-		if a.usageText == s {
-			return
-		}
-		s = a.usageText
-	}
+	a.execScopeUsageTextsLock.Lock()
+	a.execScopeUsageTexts = nil
+	a.execScopeUsageTextsLock.Unlock()
+	a.usageText = a.renderUsageLocked(nil)
 }
 
 func (a *App) createUsageLocked(execScope ...Scope) string {
-	cmdUsageText := a.Command.UsageText(execScope...)
-	text := goutil.Indent(cmdUsageText, "  ")
+	return a.renderUsageLocked(a.Command.inScopeFunc(execScope[0]))
+}
+
+// renderUsageLocked executes the usage template of the application.
+// NOTE:
+//  if @visible is not nil, only the commands it accepts are rendered.
+func (a *App) renderUsageLocked(visible func(*Command) bool) string {
 	data := map[string]interface{}{
 		"AppName":     a.appName,
 		"CmdName":     a.cmdName,
 		"Version":     a.version,
 		"Description": a.description,
 		"Authors":     a.authors,
-		"Usage":       text,
+		"Usage":       a.Command.renderUsageLocked(visible, "  "),
+		"Command":     a.Command.usageDataLocked(visible),
+		"Width":       a.usageWidthLocked(),
 		"Copyright":   a.copyright,
 	}
 	var buf bytes.Buffer
@@ -359,15 +482,13 @@ func (a *App) createUsageLocked(execScope ...Scope) string {
 		panic(err)
 	}
 	s := buf.String()
-	var usageText string
 	for {
-		usageText = strings.Replace(s, "\n\n\n", "\n\n", -1)
+		usageText := strings.Replace(s, "\n\n\n", "\n\n", -1)
 		if usageText == s {
 			return usageText
 		}
 		s = usageText
 	}
-	return usageText
 }
 
 // String makes Author comply to the Stringer interface, to allow an easy print in the templating process
@@ -1,9 +1,19 @@
 package flagx_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
 	"testing"
+	"text/template"
 	"time"
 
 	vd "github.com/bytedance/go-tagexpr/v2/validator"
@@ -180,6 +190,16 @@ func (a *Action1) Execute(c *flagx.Context) {
 	fmt.Printf("Action1: args=%+v, path=%q, object=%+v\n", c.Args(), c.CmdPathString(), a)
 }
 
+// action1Spy the Action1 that records its execution instead of printing.
+type action1Spy struct {
+	Action1
+	executed *bool
+}
+
+func (a *action1Spy) Execute(c *flagx.Context) {
+	*a.executed = true
+}
+
 type Action2 struct {
 	Name string `flag:"name;usage=param name"`
 }
@@ -286,3 +306,1240 @@ func TestScope(t *testing.T) {
 	t.Log("no scope:", app.UsageText())
 	t.Log("scope=0:", app.UsageText(flagx.Scope(0)))
 }
+
+func TestHelp(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(new(Filter1))
+	var executed bool
+	app.AddSubaction("a", "subcommand a", &action1Spy{executed: &executed})
+	b := app.AddSubcommand("b", "subcommand b", flagx.FilterFunc(Filter2))
+	{
+		b.AddSubaction("c", "subcommand c", new(Action2))
+		b.AddSubaction("d", "subcommand d", flagx.ActionFunc(Action3))
+	}
+	var buf bytes.Buffer
+	app.SetHelpOutput(&buf)
+
+	for _, args := range [][]string{
+		{"-h"},
+		{"--help"},
+		{"-g=flagx", "false", "-help"},
+		{"help"},
+	} {
+		buf.Reset()
+		stat := app.Exec(context.TODO(), args)
+		assert.True(t, stat.OK(), args)
+		assert.Equal(t, app.UsageText(), buf.String(), args)
+	}
+
+	for _, args := range [][]string{
+		{"-g=flagx", "false", "b", "c", "-h"},
+		{"-g", "flagx", "false", "b", "--help", "c", "-name", "henry"},
+		{"-g=flagx", "false", "-h", "b", "c"},
+		{"help", "b", "c"},
+	} {
+		buf.Reset()
+		stat := app.Exec(context.TODO(), args)
+		assert.True(t, stat.OK(), args)
+		assert.Equal(t, app.LookupSubcommand("b", "c").UsageText(), buf.String(), args)
+	}
+
+	// -h after the action's args prints the usage without executing it
+	buf.Reset()
+	stat := app.Exec(context.TODO(), []string{"-g=flagx", "false", "a", "-id", "1", "-h"})
+	assert.True(t, stat.OK())
+	assert.Equal(t, app.LookupSubcommand("a").UsageText(), buf.String())
+	assert.False(t, executed)
+	stat = app.Exec(context.TODO(), []string{"-g=flagx", "false", "a", "-id", "1"})
+	assert.True(t, stat.OK(), stat)
+	assert.True(t, executed)
+
+	stat = app.Exec(context.TODO(), []string{"help", "b", "x"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+}
+
+func TestRun(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetValidator(func(v interface{}) error {
+		return vd.Validate(v)
+	})
+	app.AddSubaction("a", "subcommand a", new(Action1))
+	app.AddSubaction("d", "subcommand d", flagx.ActionFunc(func(c *flagx.Context) {
+		c.ThrowStatus(100, "action d failed")
+	}))
+	var rendered []string
+	app.SetStatusRenderer(func(c *flagx.Context, stat *flagx.Status) {
+		rendered = append(rendered, fmt.Sprintf("%s: %d %s", c.CmdPathString(), stat.Code(), stat.Msg()))
+	})
+
+	assert.Equal(t, flagx.ExitCodeOK, app.Run([]string{"testapp", "a", "-id", "1"}))
+	assert.Equal(t, flagx.ExitCodeOK, app.Run([]string{"testapp", "-h"}))
+	assert.Empty(t, rendered)
+
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "a", "-id", "x"}))
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "a"}))
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "x"}))
+	assert.Equal(t, flagx.ExitCodeFailure, app.Run([]string{"testapp", "d"}))
+	app.SetExitCode(flagx.StatusNotFound, 127)
+	app.SetExitCode(100, 3)
+	assert.Equal(t, 127, app.Run([]string{"testapp", "x"}))
+	assert.Equal(t, 3, app.Run([]string{"testapp", "d"}))
+	assert.Equal(t, []string{
+		`testapp a: 3 invalid value "x" for flag -id: parse error`,
+		"testapp a: 4 empty ID",
+		`testapp x: 2 not found command action: "testapp x"`,
+		"testapp d: 100 action d failed",
+		`testapp x: 2 not found command action: "testapp x"`,
+		"testapp d: 100 action d failed",
+	}, rendered)
+}
+
+func TestDefaultStatusRenderer(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetValidator(func(v interface{}) error {
+		return vd.Validate(v)
+	})
+	app.AddSubaction("a", "subcommand a", new(Action1))
+	var stdout, stderr bytes.Buffer
+	app.SetIO(nil, &stdout, &stderr)
+	usage := "\nUSAGE:\n" +
+		"  $testapp a\n" +
+		"    subcommand a\n" +
+		"    -id int\n" +
+		"      \tparam id\n" +
+		"    ?0 string\n" +
+		"      \tparam path\n"
+
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "a", "-id", "x"}))
+	assert.Equal(t, "testapp a: invalid value \"x\" for flag -id: parse error\n"+usage, stderr.String())
+	stderr.Reset()
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "a"}))
+	assert.Equal(t, "testapp a: empty ID\n"+usage, stderr.String())
+	stderr.Reset()
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "x"}))
+	assert.Equal(t, "testapp x: not found command action: \"testapp x\"\n", stderr.String())
+	assert.Empty(t, stdout.String())
+}
+
+func TestVersion(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetVersion("v1.2.3")
+	app.SetCompiled(time.Date(2020, 2, 13, 13, 48, 15, 0, time.UTC))
+	app.SetAuthors([]flagx.Author{{
+		Name:  "henrylee2cn",
+		Email: "henrylee2cn@gmail.com",
+	}})
+	app.AddFilter(new(Filter1))
+	app.AddSubaction("a", "subcommand a", new(Action1))
+	var buf bytes.Buffer
+	app.SetHelpOutput(&buf)
+
+	const text = "testapp v1.2.3\n" +
+		"compiled: 2020-02-13 13:48:15 UTC\n" +
+		"author: henrylee2cn <henrylee2cn@gmail.com>\n"
+	assert.Equal(t, text, app.VersionText())
+	for _, args := range [][]string{
+		{"--version"},
+		{"-g", "flagx", "-version"},
+		{"version"},
+	} {
+		buf.Reset()
+		stat := app.Exec(context.TODO(), args)
+		assert.True(t, stat.OK(), args)
+		assert.Equal(t, text, buf.String(), args)
+	}
+
+	app.SetVersionTemplate(template.Must(template.New("").Parse("{{.Name}} {{.Version}}\n")))
+	buf.Reset()
+	assert.Equal(t, flagx.ExitCodeOK, app.Run([]string{"testapp", "--version"}))
+	assert.Equal(t, "testapp 1.2.3\n", buf.String())
+}
+
+type Action4 struct {
+	ID      int    `flag:"id;def=3;usage=param id with a long description that is wrapped to the usage width"`
+	Verbose bool   `flag:"verbose-output-of-the-action"`
+	Path    string `flag:"?0;usage=param path"`
+}
+
+func (a *Action4) Execute(c *flagx.Context) {}
+
+func TestAlignedUsage(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetUsageFormat(flagx.AlignedUsage)
+	app.SetUsageWidth(50)
+	app.AddFilter(new(Filter1))
+	b := app.AddSubcommand("b", "subcommand b with a long description that is wrapped")
+	b.AddSubaction("c", "subcommand c", new(Action2))
+	b.AddSubaction("e", "subcommand e", flagx.ActionFunc(func(*flagx.Context) {}))
+	b.LookupSubcommand("e").SetParentVisible(false)
+	app.AddSubaction("d", "subcommand d", flagx.ActionFunc(func(*flagx.Context) {}))
+	b.AddSubaction("f", "subcommand f", new(Action4))
+
+	assert.Equal(t, ""+
+		"testapp - v0.0.1\n"+
+		"\n"+
+		"USAGE:\n"+
+		"  -g string  global param g\n"+
+		"  ?0 bool    param view\n"+
+		"  $testapp b ...\n"+
+		"    subcommand b with a long description that is\n"+
+		"    wrapped\n"+
+		"    $testapp b c\n"+
+		"      subcommand c\n"+
+		"      -name string  param name\n"+
+		"    $testapp b f\n"+
+		"      subcommand f\n"+
+		"      -id int    param id with a long description\n"+
+		"                 that is wrapped to the usage\n"+
+		"                 width (default 3)\n"+
+		"      -verbose-output-of-the-action\n"+
+		"      ?0 string  param path\n"+
+		"  $testapp d\n"+
+		"    subcommand d\n"+
+		"\n",
+		app.UsageText(),
+	)
+	assert.True(t, strings.HasPrefix(
+		app.LookupSubcommand("b").UsageText(),
+		"$testapp b ...\n"+
+			"  subcommand b with a long description that is\n"+
+			"  wrapped\n"+
+			"  $testapp b c\n"+
+			"    subcommand c\n"+
+			"    -name string  param name\n"+
+			"  $testapp b f\n",
+	))
+
+	app.SetUsageFormat(flagx.ClassicUsage)
+	assert.Equal(t, ""+
+		"$testapp b c\n"+
+		"  subcommand c\n"+
+		"  -name string\n"+
+		"    \tparam name\n",
+		app.LookupSubcommand("b", "c").UsageText(),
+	)
+}
+
+type Action5 struct {
+	Token string `flag:"token;usage=access token;required;env=FLAGX_TEST_TOKEN"`
+	Level int    `flag:"level;def=1;usage=log level; 0 is quiet"`
+}
+
+func (a *Action5) Execute(c *flagx.Context) {
+	fmt.Printf("Action5: path=%q, object=%+v\n", c.CmdPathString(), a)
+}
+
+func TestUsageData(t *testing.T) {
+	os.Unsetenv("FLAGX_TEST_TOKEN")
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddSubaction("login", "subcommand login", new(Action5))
+
+	login := app.LookupSubcommand("login").UsageData()
+	assert.Equal(t, "testapp login", login.Path)
+	assert.True(t, login.HasAction)
+	assert.Len(t, login.Flags, 2)
+	assert.Equal(t, "-token string", login.Flags[1].Title())
+	assert.Equal(t, "access token (required) (env $FLAGX_TEST_TOKEN)", login.Flags[1].Detail())
+	assert.Equal(t, "1", login.Flags[0].Default)
+	assert.Equal(t, "log level; 0 is quiet", login.Flags[0].Usage)
+	assert.Equal(t, []*flagx.CommandUsage{login}, app.UsageData().Subcommands)
+
+	stat := app.Exec(context.TODO(), []string{"login"})
+	assert.Equal(t, flagx.StatusParseFailed, stat.Code())
+	assert.Contains(t, stat.Msg(), "required but not provided: -token")
+	os.Setenv("FLAGX_TEST_TOKEN", "abc")
+	defer os.Unsetenv("FLAGX_TEST_TOKEN")
+	stat = app.Exec(context.TODO(), []string{"login"})
+	assert.True(t, stat.OK(), stat)
+
+	app.LookupSubcommand("login").SetUsageTemplate(template.Must(template.New("login").
+		Funcs(flagx.UsageFuncMap()).
+		Parse(`{{.Title}}{{range .Flags}}
+  {{pad 16 .Title}}{{.Detail}}{{end}}
+`)))
+	app.SetUsageTemplate(template.Must(template.New("app").
+		Parse(`{{.Command.Path}} has {{len .Command.Subcommands}} subcommand(s):
+{{.Usage}}`)))
+	assert.Equal(t, ""+
+		"testapp has 1 subcommand(s):\n"+
+		"  $testapp login\n"+
+		"    -level int      log level; 0 is quiet (default 1)\n"+
+		"    -token string   access token (required) (env $FLAGX_TEST_TOKEN)\n",
+		app.UsageText(),
+	)
+}
+
+func TestAliases(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var path string
+	app.AddSubaction("delete", "subcommand delete", flagx.ActionFunc(func(c *flagx.Context) {
+		path = c.CmdPathString()
+	}))
+	del := app.LookupSubcommand("delete")
+	del.SetAliases("rm", "del")
+	assert.Equal(t, []string{"rm", "del"}, del.Aliases())
+	assert.Equal(t, del, app.LookupSubcommand("rm"))
+
+	stat := app.Exec(context.TODO(), []string{"rm"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "testapp delete", path)
+	assert.Contains(t, app.UsageText(), "$testapp delete (aliases: rm, del)\n")
+
+	assert.Panics(t, func() { app.AddSubcommand("rm", "subcommand rm") })
+	app.AddSubcommand("list", "subcommand list")
+	assert.Panics(t, func() { app.LookupSubcommand("list").SetAliases("del") })
+	assert.Panics(t, func() { app.LookupSubcommand("list").SetAliases("delete") })
+}
+
+func TestPrefixMatching(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var path string
+	action := flagx.ActionFunc(func(c *flagx.Context) {
+		path = c.CmdPathString()
+	})
+	deploy := app.AddSubcommand("deploy", "subcommand deploy")
+	deploy.AddSubaction("status", "subcommand status", action)
+	deploy.AddSubaction("start", "subcommand start", action)
+	deploy.AddSubaction("st", "subcommand st", action)
+	app.AddSubaction("describe", "subcommand describe", action)
+
+	stat := app.Exec(context.TODO(), []string{"dep", "stat"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+
+	app.SetPrefixMatching(true)
+	stat = app.Exec(context.TODO(), []string{"dep", "stat"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "testapp deploy status", path)
+	stat = app.Exec(context.TODO(), []string{"dep", "st"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "testapp deploy st", path)
+	stat = app.Exec(context.TODO(), []string{"de", "st"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+	assert.Equal(t, `ambiguous command "testapp de", candidates: deploy, describe`, stat.Msg())
+}
+
+type Action6 struct {
+	Force  bool   `flag:"force;usage=ignore the errors;deprecated=it is always forced"`
+	Secret string `flag:"secret;usage=internal secret;hidden"`
+}
+
+func (a *Action6) Execute(c *flagx.Context) {
+	fmt.Printf("Action6: path=%q, object=%+v\n", c.CmdPathString(), a)
+}
+
+func TestHiddenAndDeprecated(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var warning bytes.Buffer
+	app.SetWarningOutput(&warning)
+	app.AddSubaction("delete", "subcommand delete", new(Action6))
+	app.AddSubaction("rm", "subcommand rm", new(Action6))
+	app.LookupSubcommand("rm").SetDeprecated("use delete instead")
+	app.AddSubaction("debug", "subcommand debug", flagx.ActionFunc(func(*flagx.Context) {}))
+	app.LookupSubcommand("debug").SetHidden(true)
+
+	usage := app.UsageText()
+	assert.Contains(t, usage, "$testapp rm\n    subcommand rm (deprecated: use delete instead)\n")
+	assert.Contains(t, usage, "ignore the errors (deprecated: it is always forced)\n")
+	assert.NotContains(t, usage, "secret")
+	assert.NotContains(t, usage, "debug")
+	assert.Len(t, app.UsageData().Subcommands, 2)
+	app.AddSubcommand("internal", "internal tools").AddSubaction("dump", "subcommand dump", new(Action6))
+	app.LookupSubcommand("internal").SetHidden(true)
+	paths := func(cmds []*flagx.Command) (r []string) {
+		for _, cmd := range cmds {
+			r = append(r, cmd.PathString())
+		}
+		sort.Strings(r)
+		return r
+	}
+	assert.Equal(t, []string{"testapp", "testapp delete", "testapp rm"}, paths(app.FindActionCommands()))
+	assert.Equal(t, []string{"testapp internal", "testapp internal dump"}, paths(app.LookupSubcommand("internal").FindActionCommands()))
+
+	for i := 0; i < 2; i++ {
+		stat := app.Exec(context.TODO(), []string{"rm", "-force", "-secret=x"})
+		assert.True(t, stat.OK(), stat)
+	}
+	stat := app.Exec(context.TODO(), []string{"debug"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, ""+
+		"warning: command \"testapp rm\" is deprecated: use delete instead\n"+
+		"warning: -force of \"testapp rm\" is deprecated: it is always forced\n",
+		warning.String(),
+	)
+}
+
+func TestGroups(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(new(Filter1))
+	action := flagx.ActionFunc(func(*flagx.Context) {})
+	app.AddSubaction("version2", "print the version", action)
+	app.AddSubaction("nodes", "list the nodes", action)
+	app.AddSubaction("scale", "scale the cluster", action)
+	app.AddSubaction("trace", "trace the requests", action)
+	app.LookupSubcommand("nodes").SetGroup("Cluster")
+	app.LookupSubcommand("scale").SetGroup("Cluster")
+	app.LookupSubcommand("trace").SetGroup("Debug")
+	app.SetGroupOrder("Debug", "Cluster")
+
+	assert.Equal(t, ""+
+		"testapp - v0.0.1\n"+
+		"\n"+
+		"USAGE:\n"+
+		"  -g string\n"+
+		"    \tglobal param g\n"+
+		"  ?0 bool\n"+
+		"    \tparam view\n"+
+		"  $testapp version2\n"+
+		"    print the version\n"+
+		"  Debug commands:\n"+
+		"  $testapp trace\n"+
+		"    trace the requests\n"+
+		"  Cluster commands:\n"+
+		"  $testapp nodes\n"+
+		"    list the nodes\n"+
+		"  $testapp scale\n"+
+		"    scale the cluster\n"+
+		"\n",
+		app.UsageText(),
+	)
+
+	app.SetUsageFormat(flagx.CompactUsage)
+	assert.Equal(t, ""+
+		"testapp - v0.0.1\n"+
+		"\n"+
+		"USAGE:\n"+
+		"  -g string  global param g\n"+
+		"  ?0 bool    param view\n"+
+		"  Commands:\n"+
+		"    version2  print the version\n"+
+		"  Debug commands:\n"+
+		"    trace     trace the requests\n"+
+		"  Cluster commands:\n"+
+		"    nodes     list the nodes\n"+
+		"    scale     scale the cluster\n"+
+		"\n",
+		app.UsageText(),
+	)
+}
+
+func TestPlugins(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("the plugins are shell scripts")
+	}
+	dir, err := ioutil.TempDir("", "flagx")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	out := filepath.Join(dir, "out")
+	script := "#!/bin/sh\necho \"$@\" > " + out + "\nexit 3\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "testapp-foo"), []byte(script), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "testapp-b-bar"), []byte(script), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "testapp-baz"), []byte(script), 0644))
+	script = "#!/bin/sh\necho \"$FLAGX_TEST_PLUGIN\" > " + out + "\nexit 3\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "testapp-env"), []byte(script), 0755))
+
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetStatusRenderer(func(*flagx.Context, *flagx.Status) {})
+	app.AddSubcommand("b", "subcommand b")
+	app.SetPlugins(true, dir)
+
+	assert.Equal(t, 3, app.Run([]string{"testapp", "foo", "x", "-y"}))
+	b, _ := ioutil.ReadFile(out)
+	assert.Equal(t, "x -y\n", string(b))
+	stat := app.Exec(context.TODO(), []string{"b", "bar", "z"})
+	assert.Equal(t, flagx.StatusPluginFailed, stat.Code())
+	b, _ = ioutil.ReadFile(out)
+	assert.Equal(t, "z\n", string(b))
+	assert.Equal(t, flagx.StatusNotFound, app.Exec(context.TODO(), []string{"baz"}).Code())
+	ctx := flagx.WithEnviron(context.TODO(), []string{"FLAGX_TEST_PLUGIN=v1", "FLAGX_TEST_PLUGIN=v2"})
+	stat = app.Exec(ctx, []string{"env"})
+	assert.Equal(t, flagx.StatusPluginFailed, stat.Code())
+	b, _ = ioutil.ReadFile(out)
+	assert.Equal(t, "v2\n", string(b))
+
+	assert.Contains(t, app.UsageText(), "  Plugin commands:\n  $testapp env\n  $testapp foo\n")
+	assert.Contains(t, app.LookupSubcommand("b").UsageText(), "Plugin commands:\n$testapp b bar\n")
+
+	// the plugins are looked up by the current command name when executed
+	app.SetCmdName("otherapp")
+	assert.Equal(t, flagx.StatusNotFound, app.Exec(context.TODO(), []string{"foo"}).Code())
+	script = "#!/bin/sh\necho installed > " + out + "\nexit 3\n"
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "otherapp-qux"), []byte(script), 0755))
+	assert.Equal(t, flagx.StatusPluginFailed, app.Exec(context.TODO(), []string{"qux"}).Code())
+	b, _ = ioutil.ReadFile(out)
+	assert.Equal(t, "installed\n", string(b))
+	assert.Equal(t, flagx.StatusNotFound, app.Exec(context.TODO(), []string{"../testapp-foo"}).Code())
+	app.SetCmdName("testapp")
+	assert.Equal(t, flagx.StatusPluginFailed, app.Exec(context.TODO(), []string{"foo"}).Code())
+	app.SetPlugins(false)
+	assert.Equal(t, flagx.StatusNotFound, app.Exec(context.TODO(), []string{"foo"}).Code())
+}
+
+func TestHooks(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var events []string
+	app.AddFilter(flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+		events = append(events, "filter")
+		next(c)
+	}))
+	app.AddSubaction("a", "subcommand a", new(Action1))
+	app.SetBefore(func(c *flagx.Context) {
+		events = append(events, "before "+strings.Join(c.Args(), " "))
+	})
+	app.SetOnError(func(c *flagx.Context, stat *flagx.Status) *flagx.Status {
+		events = append(events, fmt.Sprintf("error %s %d", c.CmdPathString(), stat.Code()))
+		if stat.Code() == flagx.StatusNotFound {
+			return nil
+		}
+		return stat
+	})
+	app.SetAfter(func(c *flagx.Context, stat *flagx.Status) {
+		events = append(events, fmt.Sprintf("after %d", stat.Code()))
+	})
+
+	stat := app.Exec(context.TODO(), []string{"a", "-id=1"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, []string{"before a -id=1", "filter", "after 0"}, events)
+
+	events = nil
+	stat = app.Exec(context.TODO(), []string{"a", "-id=x"})
+	assert.Equal(t, flagx.StatusParseFailed, stat.Code())
+	assert.Equal(t, []string{"before a -id=x", "error testapp a 3", "after 3"}, events)
+
+	events = nil
+	stat = app.Exec(context.TODO(), []string{"x"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, []string{"before x", "error testapp x 2", "after 0"}, events)
+
+	events = nil
+	app.SetNotFound(func(c *flagx.Context) {
+		events = append(events, "not found")
+	})
+	stat = app.Exec(context.TODO(), []string{"x"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, []string{"before x", "not found", "after 0"}, events)
+}
+
+type ctxKey string
+
+func TestContextValue(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+		c.SetValue(ctxKey("user"), "henry")
+		next(c)
+	}))
+	b := app.AddSubcommand("b", "subcommand b", flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+		user, _ := c.GetString(ctxKey("user"))
+		c.SetValue(ctxKey("greeting"), "hello "+user)
+		c.SetValue(ctxKey("level"), 2)
+		next(c)
+	}))
+	var greeting string
+	var level int
+	var ok bool
+	b.AddSubaction("c", "subcommand c", flagx.ActionFunc(func(c *flagx.Context) {
+		greeting = c.MustValue(ctxKey("greeting")).(string)
+		level, ok = c.GetInt(ctxKey("level"))
+		_, isString := c.GetString(ctxKey("level"))
+		assert.False(t, isString)
+		assert.Panics(t, func() { c.MustValue(ctxKey("none")) })
+	}))
+	stat := app.Exec(context.TODO(), []string{"b", "c"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "hello henry", greeting)
+	assert.True(t, ok)
+	assert.Equal(t, 2, level)
+}
+
+func TestContextFilter(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(new(Filter1), flagx.FilterFunc(Filter2))
+	var f1 *Filter1
+	var f1Copy Filter1
+	var n int
+	var found, missing bool
+	app.AddSubaction("d", "subcommand d", flagx.ActionFunc(func(c *flagx.Context) {
+		n = len(c.Filters())
+		found = c.Filter(&f1) && c.Filter(&f1Copy)
+		var a1 *Action1
+		missing = !c.Filter(&a1)
+	}))
+	stat := app.Exec(context.TODO(), []string{"-g=flagx", "true", "d"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, 2, n)
+	assert.True(t, found)
+	assert.True(t, missing)
+	assert.Equal(t, &Filter1{G: "flagx", V: true}, f1)
+	assert.Equal(t, Filter1{G: "flagx", V: true}, f1Copy)
+}
+
+type (
+	Logger interface {
+		Log(string)
+	}
+	testLogger struct {
+		lines []string
+	}
+	testDB struct {
+		name string
+	}
+	Action7 struct {
+		Logger Logger  `flag:"inject"`
+		DB     *testDB `flag:"inject=db"`
+		Name   string  `flag:"name;usage=param name"`
+	}
+)
+
+func (l *testLogger) Log(s string) {
+	l.lines = append(l.lines, s)
+}
+
+func (a *Action7) Execute(c *flagx.Context) {
+	a.Logger.Log(a.DB.name + ": " + a.Name)
+}
+
+func TestInject(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	assert.Panics(t, func() { app.AddSubaction("a", "subcommand a", new(Action7)) })
+
+	logger := new(testLogger)
+	app.Provide(logger)
+	var n int
+	app.ProvideNamed("db", func() (*testDB, error) {
+		n++
+		return &testDB{name: fmt.Sprintf("db%d", n)}, nil
+	})
+	assert.Panics(t, func() { app.Provide(new(testLogger)) })
+	app.AddSubaction("b", "subcommand b", new(Action7))
+	assert.NotContains(t, app.UsageText(), "Logger")
+
+	for i := 0; i < 2; i++ {
+		stat := app.Exec(context.TODO(), []string{"b", "-name=x"})
+		assert.True(t, stat.OK(), stat)
+	}
+	assert.Equal(t, []string{"db1: x", "db2: x"}, logger.lines)
+}
+
+type Action8 struct {
+	Client *testDB
+	Tags   []string
+	Name   string `flag:"name;def=none;usage=param name"`
+}
+
+func (a *Action8) Execute(c *flagx.Context) {
+	c.SetValue(ctxKey("action"), a)
+}
+
+func TestCopyMode(t *testing.T) {
+	var got []*Action8
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetAfter(func(c *flagx.Context, stat *flagx.Status) {
+		if a, ok := c.Value(ctxKey("action")).(*Action8); ok {
+			got = append(got, a)
+		}
+	})
+	proto := &Action8{Client: &testDB{name: "db"}, Tags: []string{"a"}, Name: "x"}
+	app.AddSubaction("shallow", "", proto)
+	app.SetCopyMode(flagx.CopyDeep)
+	app.AddSubaction("deep", "", proto)
+	app.SetCopyMode(flagx.CopyZero)
+	app.AddSubaction("zero", "", proto)
+	app.AddSubaction("nil", "", (*Action8)(nil))
+	var n int
+	app.AddSubcommand("factory", "").SetActionFactory(func() flagx.Action {
+		n++
+		return &Action8{Tags: []string{fmt.Sprint(n)}}
+	})
+
+	for _, name := range []string{"shallow", "deep", "zero", "nil", "factory"} {
+		stat := app.Exec(context.TODO(), []string{name})
+		assert.True(t, stat.OK(), stat)
+	}
+	if !assert.Len(t, got, 5) {
+		return
+	}
+	assert.Equal(t, "x", proto.Name)
+	assert.Equal(t, "none", got[0].Name)
+	assert.True(t, got[0].Client == proto.Client)
+	assert.Equal(t, proto.Tags, got[0].Tags)
+	assert.Equal(t, &testDB{name: "db"}, got[1].Client)
+	assert.True(t, got[1].Client != proto.Client)
+	assert.Equal(t, proto.Tags, got[1].Tags)
+	assert.Equal(t, &Action8{Name: "none"}, got[2])
+	assert.Equal(t, &Action8{Name: "none"}, got[3])
+	assert.Equal(t, []string{fmt.Sprint(n)}, got[4].Tags)
+	assert.Equal(t, "none", got[4].Name)
+}
+
+type deepCfg struct {
+	N int
+	S string
+}
+
+type Action12 struct {
+	C *deepCfg
+	P *int
+}
+
+func (a *Action12) Execute(c *flagx.Context) {}
+
+func TestCopyDeepFieldPointer(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetCopyMode(flagx.CopyDeep)
+	cfg := &deepCfg{N: 1, S: "s"}
+	assert.NotPanics(t, func() {
+		app.AddSubaction("a", "", &Action12{C: cfg, P: &cfg.N})
+	})
+	r, stat := app.Resolve([]string{"a"})
+	if !assert.True(t, stat.OK(), stat) {
+		return
+	}
+	a := r.Action.(*Action12)
+	assert.Equal(t, cfg, a.C)
+	assert.False(t, a.C == cfg)
+	assert.Equal(t, 1, *a.P)
+	assert.False(t, a.P == &cfg.N)
+}
+
+var errTest = errors.New("test error")
+
+type Action9 struct {
+	ID int `flag:"id;usage=param id"`
+}
+
+func (a *Action9) Execute(c *flagx.Context) error {
+	switch a.ID {
+	case 1:
+		return fmt.Errorf("wrapped: %w", errTest)
+	case 2:
+		return flagx.NewStatusError(flagx.NewStatus(100, "status error", nil))
+	}
+	return nil
+}
+
+func TestActionE(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(flagx.WrapFilterE(flagx.FilterFuncE(func(c *flagx.Context, next flagx.ActionFunc) error {
+		if args := c.Args(); args[len(args)-1] == "fail" {
+			return errors.New("filter failed")
+		}
+		next(c)
+		return nil
+	})))
+	app.AddSubaction("a", "subcommand a", flagx.WrapActionE(new(Action9)))
+	app.AddSubaction("b", "subcommand b", flagx.WrapActionE(flagx.ActionFuncE(func(*flagx.Context) error {
+		return errTest
+	})))
+	app.AddSubcommand("c", "subcommand c").SetActionFactory(func() flagx.Action {
+		return flagx.WrapActionE(new(Action9))
+	})
+
+	r, stat := app.Resolve([]string{"a", "-id=1"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, &Action9{ID: 1}, r.Action)
+	assert.Contains(t, app.UsageText(), "-id int")
+	stat = app.Exec(context.TODO(), []string{"c", "-id=1"})
+	assert.Equal(t, flagx.StatusActionFailed, stat.Code())
+
+	stat = app.Exec(context.TODO(), []string{"a", "-id=0"})
+	assert.True(t, stat.OK(), stat)
+	stat = app.Exec(context.TODO(), []string{"a", "-id=1"})
+	assert.Equal(t, flagx.StatusActionFailed, stat.Code())
+	assert.Equal(t, "wrapped: test error", stat.Msg())
+	assert.True(t, errors.Is(stat.Cause(), errTest))
+	stat = app.Exec(context.TODO(), []string{"a", "-id=2"})
+	assert.Equal(t, int32(100), stat.Code())
+	assert.Equal(t, "status error", stat.Msg())
+	stat = app.Exec(context.TODO(), []string{"a", "fail"})
+	assert.Equal(t, flagx.StatusActionFailed, stat.Code())
+	assert.Equal(t, "filter failed", stat.Msg())
+
+	app.SetErrorCode(func(err error) int32 {
+		if errors.Is(err, errTest) {
+			return 101
+		}
+		return flagx.StatusActionFailed
+	})
+	stat = app.Exec(context.TODO(), []string{"b"})
+	assert.Equal(t, int32(101), stat.Code())
+}
+
+func TestPanicPolicy(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddSubaction("a", "subcommand a", flagx.ActionFunc(func(*flagx.Context) {
+		panic(errTest)
+	}))
+	app.AddSubaction("b", "subcommand b", flagx.ActionFunc(func(c *flagx.Context) {
+		c.ThrowStatus(100, "thrown")
+	}))
+
+	stat := app.Exec(context.TODO(), []string{"a"})
+	assert.Equal(t, flagx.StatusPanic, stat.Code())
+	assert.True(t, flagx.IsPanicStatus(stat))
+	assert.True(t, errors.Is(stat.Cause(), errTest))
+	var panicErr *flagx.PanicError
+	assert.True(t, errors.As(stat.Cause(), &panicErr))
+	assert.Contains(t, string(panicErr.Stack), "TestPanicPolicy")
+	stat = app.Exec(context.TODO(), []string{"b"})
+	assert.Equal(t, int32(100), stat.Code())
+	assert.False(t, flagx.IsPanicStatus(stat))
+
+	app.SetPanicPolicy(flagx.PanicRepanic)
+	assert.PanicsWithValue(t, errTest, func() { app.Exec(context.TODO(), []string{"a"}) })
+	app.SetSignals(true, time.Second)
+	assert.PanicsWithValue(t, errTest, func() { app.Exec(context.TODO(), []string{"a"}) })
+	app.LookupSubcommand("a").SetTimeout(time.Minute)
+	assert.PanicsWithValue(t, errTest, func() { app.Exec(context.TODO(), []string{"a"}) })
+	app.SetSignals(false, 0)
+	assert.PanicsWithValue(t, errTest, func() { app.Exec(context.TODO(), []string{"a"}) })
+	app.LookupSubcommand("a").SetTimeout(0)
+
+	var handled interface{}
+	app.SetPanicHandler(func(c *flagx.Context, err *flagx.PanicError) *flagx.Status {
+		handled = err.Value
+		return nil
+	})
+	stat = app.Exec(context.TODO(), []string{"a"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, errTest, handled)
+}
+
+func TestSignals(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sending signals is not supported on windows")
+	}
+	interrupt := func() {
+		p, err := os.FindProcess(os.Getpid())
+		assert.NoError(t, err)
+		assert.NoError(t, p.Signal(os.Interrupt))
+	}
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetSignals(true, time.Second)
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	defer close(release)
+	app.AddSubaction("graceful", "", flagx.ActionFunc(func(c *flagx.Context) {
+		started <- struct{}{}
+		<-c.Done()
+	}))
+	app.AddSubaction("stuck", "", flagx.ActionFunc(func(c *flagx.Context) {
+		started <- struct{}{}
+		<-c.Done()
+		started <- struct{}{}
+		// the aborted action keeps using its context
+		c.SetValue(ctxKey("stuck"), true)
+		<-release
+	}))
+	var afterPath string
+	var afterValue interface{}
+	app.SetAfter(func(c *flagx.Context, stat *flagx.Status) {
+		afterPath = c.CmdPathString()
+		afterValue = c.Value(ctxKey("stuck"))
+	})
+
+	go func() {
+		<-started
+		interrupt()
+	}()
+	stat := app.Exec(context.TODO(), []string{"graceful"})
+	assert.True(t, stat.OK(), stat)
+
+	go func() {
+		<-started
+		interrupt()
+		<-started
+		interrupt()
+	}()
+	stat = app.Exec(context.TODO(), []string{"stuck"})
+	assert.Equal(t, flagx.StatusInterrupted, stat.Code())
+	assert.Equal(t, flagx.ExitCodeSignal, app.ExitCode(stat))
+	assert.Equal(t, "testapp", afterPath)
+	assert.Nil(t, afterValue)
+}
+
+func crashInAction() {
+	panic("crash")
+}
+
+func TestTimeout(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetTimeoutOption(true)
+	app.SetTimeoutGrace(200 * time.Millisecond)
+	release := make(chan struct{})
+	defer close(release)
+	app.AddSubaction("graceful", "graceful action", flagx.ActionFunc(func(c *flagx.Context) {
+		<-c.Done()
+		assert.Equal(t, context.DeadlineExceeded, c.Err())
+		c.SetValue(ctxKey("graceful"), "done")
+	}))
+	app.AddSubaction("stuck", "stuck action", flagx.ActionFunc(func(c *flagx.Context) {
+		<-release
+	}))
+	var remaining time.Duration
+	app.AddSubaction("quick", "quick action", flagx.ActionFunc(func(c *flagx.Context) {
+		deadline, _ := c.Deadline()
+		remaining = time.Until(deadline)
+	}))
+	app.AddSubaction("crash", "crash action", flagx.ActionFunc(func(c *flagx.Context) {
+		crashInAction()
+	}))
+	var afterErr error
+	var afterValue interface{}
+	app.SetAfter(func(c *flagx.Context, stat *flagx.Status) {
+		afterErr = c.Err()
+		afterValue = c.Value(ctxKey("graceful"))
+	})
+	app.SetTimeout(time.Hour)
+	app.LookupSubcommand("graceful").SetTimeout(10 * time.Millisecond)
+	app.LookupSubcommand("stuck").SetTimeout(10 * time.Millisecond)
+
+	stat := app.Exec(context.TODO(), []string{"graceful"})
+	assert.True(t, stat.OK(), stat)
+	assert.NoError(t, afterErr)
+	assert.Equal(t, "done", afterValue)
+	stat = app.Exec(context.TODO(), []string{"crash"})
+	assert.Equal(t, flagx.StatusPanic, stat.Code())
+	var panicErr *flagx.PanicError
+	if assert.True(t, errors.As(stat.Cause(), &panicErr)) {
+		assert.Equal(t, "crash", panicErr.Value)
+		assert.Contains(t, string(panicErr.Stack), "crashInAction")
+	}
+	stat = app.Exec(context.TODO(), []string{"stuck"})
+	assert.Equal(t, flagx.StatusTimeout, stat.Code())
+	assert.Equal(t, "timed out after 10ms", stat.Msg())
+	stat = app.Exec(context.TODO(), []string{"quick"})
+	assert.True(t, stat.OK(), stat)
+	assert.True(t, remaining > time.Minute)
+	stat = app.Exec(context.TODO(), []string{"quick", "--timeout", "1s"})
+	assert.True(t, stat.OK(), stat)
+	assert.True(t, remaining <= time.Second)
+	stat = app.Exec(context.TODO(), []string{"--timeout=x", "quick"})
+	assert.Equal(t, flagx.StatusBadArgs, stat.Code())
+
+	usage := app.UsageText()
+	assert.Contains(t, usage, "(default 1h0m0s)")
+	assert.Contains(t, usage, "graceful action (timeout 10ms)")
+	assert.Contains(t, usage, "-timeout duration")
+}
+
+type Action10 struct {
+	Token string `flag:"token;required;env=TOKEN"`
+}
+
+func (a *Action10) Execute(c *flagx.Context) {
+	fmt.Fprintf(c.Stdout(), "token=%s home=%s", a.Token, c.Getenv("HOME"))
+}
+
+func TestIO(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.SetEnv(func(key string) (string, bool) {
+		return "", false
+	})
+	app.AddSubaction("login", "subcommand login", new(Action10))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var stdout, stderr bytes.Buffer
+			env := map[string]string{"TOKEN": fmt.Sprint(i), "HOME": "/home/" + fmt.Sprint(i)}
+			ctx := flagx.WithIO(context.TODO(), nil, &stdout, &stderr)
+			ctx = flagx.WithEnv(ctx, func(key string) (string, bool) {
+				v, ok := env[key]
+				return v, ok
+			})
+			stat := app.Exec(ctx, []string{"login"})
+			assert.True(t, stat.OK(), stat)
+			assert.Equal(t, fmt.Sprintf("token=%d home=/home/%d", i, i), stdout.String())
+			assert.Empty(t, stderr.String())
+		}()
+	}
+	wg.Wait()
+
+	var stdout, stderr bytes.Buffer
+	app.SetIO(nil, &stdout, &stderr)
+	stat := app.Exec(context.TODO(), []string{"login"})
+	assert.Equal(t, flagx.StatusParseFailed, stat.Code())
+	assert.Contains(t, stderr.String(), "required but not provided: -token")
+	stat = app.Exec(context.TODO(), []string{"login", "-h"})
+	assert.True(t, stat.OK(), stat)
+	assert.Contains(t, stdout.String(), "$testapp login")
+	stderr.Reset()
+	assert.Equal(t, flagx.ExitCodeUsage, app.Run([]string{"testapp", "logout"}))
+	assert.Equal(t, "testapp logout: not found command action: \"testapp logout\"\n", stderr.String())
+}
+
+type Filter3 struct {
+	G string `flag:"g;usage=global param g"`
+}
+
+func (f *Filter3) Filter(c *flagx.Context, next flagx.ActionFunc) {
+	next(c)
+}
+
+func TestResolve(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var executed bool
+	app.AddFilter(new(Filter3), flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+		executed = true
+		next(c)
+	}))
+	b := app.AddSubcommand("b", "subcommand b")
+	b.AddSubaction("c", "subcommand c", new(Action2))
+	app.SetNotFound(func(*flagx.Context) { executed = true })
+
+	r, stat := app.Resolve([]string{"-g=x", "b", "c", "-name", "henry", "rest"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, b.LookupSubcommand("c"), r.Command)
+	assert.Equal(t, []string{"testapp", "b", "c"}, r.CmdPath)
+	assert.Len(t, r.Filters, 2)
+	assert.Equal(t, &Filter3{G: "x"}, r.Filters[0])
+	assert.Equal(t, &Action2{Name: "henry"}, r.Action)
+	assert.Equal(t, []string{"rest"}, r.Args)
+
+	r, stat = app.Resolve([]string{"b", "x"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+	assert.Equal(t, []string{"testapp", "b", "x"}, r.CmdPath)
+	assert.Nil(t, r.Action)
+	_, stat = app.Resolve([]string{"b", "c", "-name"})
+	assert.Equal(t, flagx.StatusParseFailed, stat.Code())
+	assert.False(t, executed)
+}
+
+func TestContextExec(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var filtered int
+	app.AddFilter(flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+		filtered++
+		next(c)
+	}))
+	app.AddSubaction("build", "", flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprintf(c.Stdout(), "build %v %v;", c.Args(), c.Value(ctxKey("version")))
+	}))
+	app.AddSubaction("test", "", flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprint(c.Stdout(), "test;")
+	}))
+	app.AddSubaction("release", "", flagx.ActionFunc(func(c *flagx.Context) {
+		c.SetValue(ctxKey("version"), "v1")
+		stat := c.Exec([]string{"build"}, []string{"-os=linux"})
+		if !stat.OK() {
+			panic(stat)
+		}
+		stat = c.ExecCommand(app.LookupSubcommand("test"), nil, true)
+		if !stat.OK() {
+			panic(stat)
+		}
+	}))
+	app.AddSubaction("loop", "", flagx.ActionFunc(func(c *flagx.Context) {
+		stat := c.Exec([]string{"loop"}, nil)
+		if !stat.OK() {
+			panic(stat)
+		}
+	}))
+
+	var stdout bytes.Buffer
+	stat := app.Exec(flagx.WithIO(context.TODO(), nil, &stdout, nil), []string{"release"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "build [build -os=linux] v1;test;", stdout.String())
+	assert.Equal(t, 2, filtered)
+
+	stat = app.Exec(context.TODO(), []string{"loop"})
+	assert.Equal(t, flagx.StatusRecursion, stat.Code())
+	assert.Equal(t, `recursive execution: "testapp loop -> testapp loop"`, stat.Msg())
+}
+
+type Filter4 struct {
+	V bool `flag:"?0"`
+}
+
+func (f *Filter4) Filter(c *flagx.Context, next flagx.ActionFunc) {
+	fmt.Fprintf(c.Stdout(), "filter %v;", f.V)
+	next(c)
+}
+
+func TestContextExecPath(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(new(Filter4))
+	app.AddSubaction("deploy", "", new(Action11))
+	app.AddSubaction("release", "", flagx.ActionFunc(func(c *flagx.Context) {
+		stat := c.Exec([]string{"deploy"}, []string{"-env", "prod"})
+		if !stat.OK() {
+			panic(stat)
+		}
+	}))
+	app.AddSubaction("missing", "", flagx.ActionFunc(func(c *flagx.Context) {
+		panic(c.Exec([]string{"x", "y"}, nil))
+	}))
+
+	var stdout bytes.Buffer
+	ctx := flagx.WithIO(context.TODO(), nil, &stdout, nil)
+	stat := app.Exec(ctx, []string{"true", "release"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "filter true;filter true;testapp deploy prod;", stdout.String())
+
+	stat = app.Exec(ctx, []string{"false", "missing"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+	assert.Equal(t, `not found command action: "testapp x"`, stat.Msg())
+}
+
+type Filter5 struct {
+	Token string `flag:"token;required"`
+}
+
+func (f *Filter5) Filter(c *flagx.Context, next flagx.ActionFunc) {
+	fmt.Fprintf(c.Stdout(), "token %s;", f.Token)
+	next(c)
+}
+
+func TestContextExecRequiredFilter(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddFilter(new(Filter5))
+	app.AddSubaction("deploy", "", new(Action11))
+	app.AddSubaction("release", "", flagx.ActionFunc(func(c *flagx.Context) {
+		stat := c.Exec([]string{"deploy"}, []string{"-env", "prod"})
+		if !stat.OK() {
+			panic(stat)
+		}
+	}))
+
+	var stdout, stderr bytes.Buffer
+	ctx := flagx.WithIO(context.TODO(), nil, &stdout, &stderr)
+	stat := app.Exec(ctx, []string{"-token=abc", "release"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "token abc;token abc;testapp deploy prod;", stdout.String())
+	assert.Empty(t, stderr.String())
+}
+
+type Action11 struct {
+	Env string `flag:"env;usage=target environment"`
+}
+
+func (a *Action11) Execute(c *flagx.Context) {
+	if a.Env == "" {
+		c.ThrowStatus(100, "env is required")
+	}
+	fmt.Fprintf(c.Stdout(), "%s %s;", c.CmdPathString(), a.Env)
+}
+
+func TestChainable(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	var filtered int
+	app.AddFilter(flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+		filtered++
+		next(c)
+	}))
+	app.AddSubaction("build", "", flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprint(c.Stdout(), "build;")
+	}))
+	app.AddSubaction("test", "", flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprint(c.Stdout(), "test;")
+	}))
+	app.AddSubaction("deploy", "", new(Action11))
+	app.LookupSubcommand("build").SetChainable(true)
+	app.LookupSubcommand("test").SetChainable(true)
+
+	var stdout bytes.Buffer
+	ctx := flagx.WithIO(context.TODO(), nil, &stdout, nil)
+	stat := app.Exec(ctx, []string{"build", "test", "deploy", "-env", "prod"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "build;test;testapp deploy prod;", stdout.String())
+	assert.Equal(t, 1, filtered)
+
+	stdout.Reset()
+	ctxObj, stat := app.ExecContext(ctx, []string{"build", "deploy", "test"})
+	assert.Equal(t, int32(100), stat.Code())
+	assert.Equal(t, `step 2 "testapp deploy": env is required`, stat.Msg())
+	assert.Equal(t, "testapp deploy", ctxObj.CmdPathString())
+	assert.Equal(t, "build;", stdout.String())
+
+	stat = app.Exec(ctx, []string{"build", "x"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+	assert.Equal(t, `step 2 "testapp x": not found command action: "testapp x"`, stat.Msg())
+
+	r, stat := app.Resolve([]string{"build", "test", "-v"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, []string{"test", "-v"}, r.Args)
+}
+
+func TestChainableFilters(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	newFilter := func(name string) flagx.Filter {
+		return flagx.FilterFunc(func(c *flagx.Context, next flagx.ActionFunc) {
+			fmt.Fprintf(c.Stdout(), "%s start;", name)
+			next(c)
+			fmt.Fprintf(c.Stdout(), "%s end;", name)
+		})
+	}
+	app.AddFilter(newFilter("root"))
+	app.AddSubcommand("build", "", newFilter("build")).SetAction(flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprint(c.Stdout(), "build;")
+	}))
+	app.AddSubcommand("test", "", newFilter("test")).SetAction(flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprint(c.Stdout(), "test;")
+	}))
+	app.LookupSubcommand("build").SetChainable(true)
+
+	var stdout bytes.Buffer
+	stat := app.Exec(flagx.WithIO(context.TODO(), nil, &stdout, nil), []string{"build", "test"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, "root start;build start;build;build end;test start;test;test end;root end;", stdout.String())
+}
+
+func TestDefault(t *testing.T) {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	status := app.AddSubcommand("status", "subcommand status")
+	status.AddSubaction("show", "subcommand show", new(Action2))
+	status.AddSubaction("watch", "subcommand watch", flagx.ActionFunc(func(*flagx.Context) {}))
+	assert.Panics(t, func() { status.SetDefault("x") })
+	status.SetDefault("show")
+	assert.Equal(t, "show", status.Default())
+
+	r, stat := app.Resolve([]string{"status", "-name", "henry"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, []string{"testapp", "status", "show"}, r.CmdPath)
+	assert.Equal(t, &Action2{Name: "henry"}, r.Action)
+	r, stat = app.Resolve([]string{"status", "watch"})
+	assert.True(t, stat.OK(), stat)
+	assert.Equal(t, []string{"testapp", "status", "watch"}, r.CmdPath)
+	_, stat = app.Resolve([]string{"status", "x"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+
+	assert.Contains(t, app.UsageText(), "$testapp status show (default)\n")
+	assert.NotContains(t, app.UsageText(), "$testapp status watch (default)")
+	status.SetDefault("")
+	_, stat = app.Resolve([]string{"status"})
+	assert.Equal(t, flagx.StatusNotFound, stat.Code())
+	assert.NotContains(t, app.UsageText(), "(default)")
+}
@@ -0,0 +1,82 @@
+package flagx
+
+import (
+	"fmt"
+)
+
+// SetChainable sets whether the arguments left by the action of the command are
+// executed as another command at the same level, such as `app build test -v`
+// executing `app test -v` after `app build` if build is chainable.
+// NOTE:
+//  the arguments left are the ones after the flags and non-flags of the action,
+//  see FlagSet.NextArgs, and all the arguments of a function action, such as ActionFunc;
+//  the chained command continues the chain only if it is chainable too;
+//  the filters of the parent commands wrap the whole chain, while the filters of
+//  each chained command wrap its own step only;
+//  the chain stops at the first failed command, whose status message tells the step.
+func (c *Command) SetChainable(chainable bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.chainable = chainable
+}
+
+// Chainable reports whether the arguments left by the action are executed as another command.
+func (c *Command) Chainable() bool {
+	return c.chainable
+}
+
+// withChain returns the action that calls @fn, then executes the commands chained
+// by the arguments left, each of which is one step of the chain.
+func (a *App) withChain(fn ActionFunc) ActionFunc {
+	return func(ctxObj *Context) {
+		shared := ctxObj.filters[:len(ctxObj.filters)-ctxObj.ownFilterCount()]
+		a.execStep(ctxObj, 1, len(ctxObj.nextArgs) > 0, fn)
+		for step := 2; ctxObj.cmd.chainable && len(ctxObj.nextArgs) > 0; step++ {
+			parent := ctxObj.cmd.parent
+			ctxObj.args = ctxObj.nextArgs
+			ctxObj.nextArgs = nil
+			ctxObj.cmdPath = parent.Path()
+			ctxObj.skipped = make(map[*Command]bool)
+			for cmd := parent; cmd != nil; cmd = cmd.parent {
+				ctxObj.skipped[cmd] = true
+			}
+			a.execStep(ctxObj, step, true, func(ctxObj *Context) {
+				parent.lock.RLock()
+				filters, action, found := parent.findFiltersAndAction(ctxObj, ctxObj.args)
+				parent.lock.RUnlock()
+				if !found {
+					ThrowStatus(
+						StatusNotFound,
+						"",
+						fmt.Sprintf("not found command action: %q", ctxObj.CmdPathString()),
+					)
+				}
+				ctxObj.filters = append(shared[:len(shared):len(shared)], filters...)
+				ctxObj.action = action
+				a.withFilters(filters, a.toActionFunc(action))(ctxObj)
+			})
+		}
+	}
+}
+
+// ownFilterCount returns the number of the filters created for the command reached,
+// which are the last ones of the context filters.
+func (c *Context) ownFilterCount() int {
+	if c.skipped[c.cmd] {
+		return 0
+	}
+	return len(c.cmd.filters)
+}
+
+// execStep calls the step of the chain, and panics its failed status,
+// whose message tells the step if it is chained.
+func (a *App) execStep(ctxObj *Context, step int, chained bool, fn ActionFunc) {
+	stat := a.catchStatus(ctxObj, func() { fn(ctxObj) })
+	if stat.OK() {
+		return
+	}
+	if chained {
+		stat = NewStatus(stat.Code(), fmt.Sprintf("step %d %q: %s", step, ctxObj.CmdPathString(), stat.Msg()), stat.Cause())
+	}
+	panic(stat)
+}
@@ -1,16 +1,17 @@
 package flagx
 
 import (
-	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"reflect"
 	"sort"
 	"strings"
 	"sync"
+	"text/template"
+	"time"
 
 	"github.com/henrylee2cn/ameda"
-	"github.com/henrylee2cn/goutil/status"
 )
 
 // Command a command object
@@ -23,12 +24,21 @@ type Command struct {
 	filters                 []*filterObject
 	action                  *actionObject
 	subcommands             map[string]*Command
+	aliasSubcommands        map[string]*Command // subcommands by alias
+	aliases                 []string
+	hidden                  bool
+	group                   string
+	deprecated              string
+	timeout                 time.Duration
+	chainable               bool
+	defaultSubcommand       string
 	scopeCommandMap         map[Scope][]*Command // commands with actions by scope
 	scopeCommands           []*Command           // commands with actions by scope
 	usageText               string
 	execScopeUsageTexts     map[Scope]string
 	execScopeUsageTextsLock sync.RWMutex
 	parentUsageVisible      bool
+	usageTemplate           *template.Template
 	meta                    map[interface{}]interface{}
 	lock                    sync.RWMutex
 }
@@ -62,6 +72,7 @@ func (c *Command) GetMeta(key interface{}) interface{} {
 
 // AddSubaction adds a subcommand and its action.
 // NOTE:
+//  ActionE can be added by WrapActionE;
 //  panic when something goes wrong
 func (c *Command) AddSubaction(cmdName, description string, action Action, scope ...Scope) {
 	c.AddSubcommand(cmdName, description).SetAction(action, scope...)
@@ -69,6 +80,7 @@ func (c *Command) AddSubaction(cmdName, description string, action Action, scope
 
 // AddSubcommand adds a subcommand.
 // NOTE:
+//  FilterE can be added by WrapFilterE;
 //  panic when something goes wrong
 func (c *Command) AddSubcommand(cmdName, description string, filters ...Filter) *Command {
 	if cmdName == "" {
@@ -79,7 +91,7 @@ func (c *Command) AddSubcommand(cmdName, description string, filters ...Filter)
 	if c.action != nil {
 		panic(fmt.Errorf("action has been set, no subcommand can be set: %q", c.PathString()))
 	}
-	if c.subcommands[cmdName] != nil {
+	if c.lookupSubcommandLocked(cmdName) != nil {
 		panic(fmt.Errorf("action named %s already exists", cmdName))
 	}
 	subCmd := newCommand(c.app, cmdName, description)
@@ -89,25 +101,148 @@ func (c *Command) AddSubcommand(cmdName, description string, filters ...Filter)
 	return subCmd
 }
 
+// SetAliases sets the alternative names of the subcommand,
+// which are resolved in the same way as the name.
+// NOTE:
+//  panic when something goes wrong
+func (c *Command) SetAliases(aliases ...string) {
+	p := c.parent
+	if p == nil {
+		panic(fmt.Errorf("the root command can not have aliases: %q", c.PathString()))
+	}
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, alias := range aliases {
+		if alias == "" {
+			panic("command alias is empty")
+		}
+		if sub := p.lookupSubcommandLocked(alias); sub != nil && sub != c {
+			panic(fmt.Errorf("action named %s already exists", alias))
+		}
+	}
+	for _, alias := range c.aliases {
+		delete(p.aliasSubcommands, alias)
+	}
+	if p.aliasSubcommands == nil {
+		p.aliasSubcommands = make(map[string]*Command, len(aliases))
+	}
+	c.aliases = make([]string, 0, len(aliases))
+	for _, alias := range aliases {
+		if alias == c.cmdName || p.aliasSubcommands[alias] == c {
+			continue
+		}
+		p.aliasSubcommands[alias] = c
+		c.aliases = append(c.aliases, alias)
+	}
+	c.app.updateUsageLocked()
+}
+
+// Aliases returns the alternative names of the command.
+func (c *Command) Aliases() []string {
+	return c.aliases
+}
+
+// SetGroup sets the group under which the command is listed in the usage
+// of its parent, such as `Cluster` for the `Cluster commands:` section.
+// NOTE:
+//  the ungrouped commands are listed first, see App.SetGroupOrder for the groups.
+func (c *Command) SetGroup(name string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.group = name
+	c.app.updateUsageLocked()
+}
+
+// Group returns the group of the command.
+func (c *Command) Group() string {
+	return c.group
+}
+
+// SetHidden sets whether the command is hidden from the usage everywhere,
+// it can still be executed by its name.
+func (c *Command) SetHidden(hidden bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.hidden = hidden
+	c.app.updateUsageLocked()
+}
+
+// Hidden reports whether the command is hidden from the usage.
+func (c *Command) Hidden() bool {
+	return c.hidden
+}
+
+// SetDeprecated marks the command as deprecated,
+// the message is shown in the usage and the warning when it is executed.
+// NOTE:
+//  if message is empty, the command is no longer deprecated.
+func (c *Command) SetDeprecated(message string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.deprecated = message
+	c.app.updateUsageLocked()
+}
+
+// Deprecated returns the deprecation message of the command,
+// returning empty if it is not deprecated.
+func (c *Command) Deprecated() string {
+	return c.deprecated
+}
+
+// SetDefault sets the subcommand executed when no subcommand is given,
+// such as `app status -v` executing `app status show -v`.
+// NOTE:
+//  if subCmdName is empty, there is no default subcommand;
+//  panic when the subcommand does not exist.
+func (c *Command) SetDefault(subCmdName string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if subCmdName != "" && c.subcommands[subCmdName] == nil {
+		panic(fmt.Errorf("not found subcommand %q of %q", subCmdName, c.PathString()))
+	}
+	c.defaultSubcommand = subCmdName
+	c.app.updateUsageLocked()
+}
+
+// Default returns the name of the subcommand executed when no subcommand is given.
+func (c *Command) Default() string {
+	return c.defaultSubcommand
+}
+
+// HasAction reports whether the action of the command has been set,
+// otherwise it is a group of subcommands.
+func (c *Command) HasAction() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.action != nil
+}
+
 // AddFilter adds the filter action.
 // NOTE:
-//  if filter is a struct, it can implement the copier interface;
+//  FilterE can be added by WrapFilterE;
+//  if filter is a struct, it is the prototype copied for each execution,
+//  see App.SetCopyMode, or it can implement the copier interface;
 //  panic when something goes wrong
 func (c *Command) AddFilter(filters ...Filter) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
-	for _, filter := range filters {
+	for _, f := range filters {
+		filter := unwrapFilter(f)
 		var obj filterObject
 		obj.flagSet = NewFlagSet(c.cmdName, ContinueOnError|ContinueOnUndefined)
 		elemType := ameda.DereferenceType(reflect.TypeOf(filter))
 		switch elemType.Kind() {
 		case reflect.Struct:
-			var ok bool
-			obj.factory, ok = filter.(FilterCopier)
-			if !ok {
-				obj.factory = &factory{elemType: elemType}
+			if copier, ok := filter.(FilterCopier); ok {
+				obj.newObject = func() interface{} { return unwrapFilter(copier.DeepCopy()) }
+			} else {
+				obj.newObject = c.app.newActionFactory(elemType, filter).newObject
 			}
-			err := obj.flagSet.StructVars(obj.factory.DeepCopy())
+			err := obj.flagSet.StructVars(obj.newObject())
+			if err != nil {
+				panic(err)
+			}
+			err = c.app.checkInjection(elemType)
 			if err != nil {
 				panic(err)
 			}
@@ -118,7 +253,7 @@ func (c *Command) AddFilter(filters ...Filter) {
 				obj.options[f.Name] = f
 			})
 		case reflect.Func:
-			obj.filterFunc = filter.Filter
+			obj.filterFunc = c.app.toFilterFunc(filter)
 		}
 		c.filters = append(c.filters, &obj)
 	}
@@ -127,9 +262,20 @@ func (c *Command) AddFilter(filters ...Filter) {
 
 // SetAction sets the action of the command.
 // NOTE:
-//  if action is a struct, it can implement the copier interface;
+//  ActionE can be set by WrapActionE;
+//  if action is a struct, it is the prototype copied for each execution,
+//  see App.SetCopyMode, or it can implement the copier interface;
 //  panic when something goes wrong.
 func (c *Command) SetAction(action Action, scope ...Scope) {
+	c.setAction(unwrapAction(action), nil, scope...)
+}
+
+func (c *Command) setAction(action interface{}, copier ActionCopier, scope ...Scope) {
+	switch action.(type) {
+	case Action, ActionE:
+	default:
+		panic(fmt.Errorf("flagx: want Action or ActionE, but got %T", action))
+	}
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	if len(c.subcommands) > 0 {
@@ -144,12 +290,19 @@ func (c *Command) SetAction(action Action, scope ...Scope) {
 	elemType := ameda.DereferenceType(reflect.TypeOf(action))
 	switch elemType.Kind() {
 	case reflect.Struct:
-		var ok bool
-		obj.actionFactory, ok = action.(ActionCopier)
-		if !ok {
-			obj.actionFactory = &actionFactory{elemType: elemType}
+		if copier == nil {
+			copier, _ = action.(ActionCopier)
 		}
-		err := obj.flagSet.StructVars(obj.actionFactory.DeepCopy())
+		if copier != nil {
+			obj.newObject = func() interface{} { return unwrapAction(copier.DeepCopy()) }
+		} else {
+			obj.newObject = c.app.newActionFactory(elemType, action).newObject
+		}
+		err := obj.flagSet.StructVars(obj.newObject())
+		if err != nil {
+			panic(err)
+		}
+		err = c.app.checkInjection(elemType)
 		if err != nil {
 			panic(err)
 		}
@@ -160,7 +313,7 @@ func (c *Command) SetAction(action Action, scope ...Scope) {
 			obj.options[f.Name] = f
 		})
 	case reflect.Func:
-		obj.actionFunc = action.Execute
+		obj.actionFunc = c.app.toActionFunc(action)
 	}
 	c.action = &obj
 	if len(scope) > 0 {
@@ -200,80 +353,178 @@ func cmdsDistinctAndSort(cmds []*Command) []*Command {
 // Exec executes the command.
 // NOTE:
 //  @arguments does not contain the command name;
-//  the default value of @scope is 0.
+//  the default value of @scope is 0;
+//  `-h`/`--help` at any depth and `help [command...]` print the usage;
+//  `--version` and `version` of the app print the version.
 func (c *Command) Exec(ctx context.Context, arguments []string, execScope ...Scope) (stat *Status) {
-	defer status.Catch(&stat)
+	_, stat = c.exec(ctx, arguments, execScope, nil)
+	return
+}
+
+// ExecContext executes the command in the same way as Exec, and returns the context
+// of the execution, which holds the command reached even if the execution fails.
+func (c *Command) ExecContext(ctx context.Context, arguments []string, execScope ...Scope) (*Context, *Status) {
+	return c.exec(ctx, arguments, execScope, nil)
+}
+
+// exec executes the command and returns the context,
+// which holds the command reached even if the execution fails.
+// NOTE:
+//  if @flagOutput is not nil, the flag parsing messages are written to it.
+func (c *Command) exec(ctx context.Context, arguments []string, execScope []Scope, flagOutput io.Writer) (ctxObj *Context, stat *Status) {
+	ctx, abort, stopSignals := c.app.notifySignals(ctx)
+	defer stopSignals()
+	ctxObj = c.newContext(ctx, arguments, execScope, flagOutput)
+	before, onError, after := c.app.hooks()
+	// the body runs on a copy, which is not shared with the hooks if it is aborted
+	bodyCtx := *ctxObj
+	stat, aborted := execAbortable(func() *Status {
+		ctxObj := &bodyCtx
+		return c.app.catchStatus(ctxObj, func() {
+			if before != nil {
+				before(ctxObj)
+			}
+			arguments, ctxObj.timeout = c.app.parseTimeoutOption(arguments)
+			ctxObj.args = arguments
+			if c.execVersion(ctxObj, arguments) || c.execHelp(ctxObj, arguments, execScope) {
+				return
+			}
+			handle := c.app.withTimeout(ctxObj, c.route(ctxObj))
+			handle(ctxObj)
+		})
+	}, abort)
+	if !aborted {
+		*ctxObj = bodyCtx
+	}
+	if !stat.OK() && onError != nil {
+		failed := stat
+		if hookStat := c.app.catchStatus(ctxObj, func() { stat = onError(ctxObj, failed) }); !hookStat.OK() {
+			stat = hookStat
+		}
+	}
+	if after != nil {
+		if hookStat := c.app.catchStatus(ctxObj, func() { after(ctxObj, stat) }); !hookStat.OK() {
+			stat = hookStat
+		}
+	}
+	return
+}
+
+// newContext returns the context of the execution from the command.
+func (c *Command) newContext(ctx context.Context, arguments []string, execScope []Scope, flagOutput io.Writer) *Context {
 	var s Scope
 	if len(execScope) > 0 {
 		s = execScope[0]
 	}
-	handle, ctxObj := c.route(ctx, arguments, s)
-	handle(ctxObj)
-	return
+	ctxObj := &Context{args: arguments, cmdPath: []string{c.cmdName}, Context: ctx, cmd: c, execScope: s, flagOutput: flagOutput}
+	ctxObj.initIO(c.app)
+	return ctxObj
+}
+
+func (c *Command) route(ctxObj *Context) ActionFunc {
+	return c.routeFrom(ctxObj, nil, ctxObj.args)
 }
 
-func (c *Command) route(ctx context.Context, arguments []string, execScope Scope) (ActionFunc, *Context) {
+// routeFrom routes the arguments from the command, whose action is wrapped by
+// @parentFilters before the filters created by the routing.
+func (c *Command) routeFrom(ctxObj *Context, parentFilters []interface{}, arguments []string) ActionFunc {
 	c.lock.RLock()
 	defer c.lock.RUnlock()
-	filters, action, cmdPath, cmd, found := c.findFiltersAndAction([]string{c.cmdName}, arguments, execScope)
-	actionFunc := action.Execute
+	filters, action, found := c.findFiltersAndAction(ctxObj, arguments)
+	actionFunc := c.app.toActionFunc(action)
 	if found {
-		for i := len(filters) - 1; i >= 0; i-- {
-			filter := filters[i]
-			nextAction := actionFunc
-			actionFunc = func(c *Context) {
-				filter.Filter(c, nextAction)
-			}
+		filters = append(parentFilters[:len(parentFilters):len(parentFilters)], filters...)
+		ctxObj.filters = filters
+		ctxObj.action = action
+		if ctxObj.cmd.chainable {
+			// the own filters wrap the first step only, and the shared ones the chain
+			n := len(filters) - ctxObj.ownFilterCount()
+			actionFunc = c.app.withChain(c.app.withFilters(filters[n:], actionFunc))
+			filters = filters[:n]
 		}
+		actionFunc = c.app.withFilters(filters, actionFunc)
 	}
-	return actionFunc, &Context{args: arguments, cmdPath: cmdPath, Context: ctx, cmd: cmd, execScope: execScope}
+	return actionFunc
 }
 
-func (c *Command) findFiltersAndAction(cmdPath, arguments []string, execScope Scope) ([]Filter, Action, []string, *Command, bool) {
+// withFilters returns the action wrapped by the filters from the outermost to the innermost.
+func (a *App) withFilters(filters []interface{}, actionFunc ActionFunc) ActionFunc {
+	for i := len(filters) - 1; i >= 0; i-- {
+		filter := a.toFilterFunc(filters[i])
+		nextAction := actionFunc
+		actionFunc = func(c *Context) {
+			filter(c, nextAction)
+		}
+	}
+	return actionFunc
+}
+
+// findFiltersAndAction creates the filters and action matched by the arguments,
+// and records the command reached in @ctxObj.
+func (c *Command) findFiltersAndAction(ctxObj *Context, arguments []string) ([]interface{}, interface{}, bool) {
+	ctxObj.cmd = c
 	if c.action != nil && c.app.scopeMatcherFunc != nil {
-		CheckStatus(c.app.scopeMatcherFunc(c.scope, execScope), StatusMismatchScope, "")
+		CheckStatus(c.app.scopeMatcherFunc(c.scope, ctxObj.execScope), StatusMismatchScope, "")
 	}
-	filters, arguments := c.newFilters(arguments)
-	action, arguments, found := c.newAction(arguments)
+	var filters []interface{}
+	if !ctxObj.skipped[c] {
+		filters, arguments = c.newFilters(ctxObj, arguments)
+	}
+	action, arguments, found := c.newAction(ctxObj, arguments)
 	if found {
-		return filters, action, cmdPath, c, true
+		ctxObj.nextArgs = arguments
+		return filters, action, true
 	}
 	subCmdName, arguments := SplitArgs(arguments)
-	subCmd := c.subcommands[subCmdName]
-	if subCmdName != "" {
-		cmdPath = append(cmdPath, subCmdName)
+	if subCmdName == "" {
+		subCmdName = c.defaultSubcommand
+	}
+	subCmd := c.matchSubcommandLocked(subCmdName)
+	if subCmd != nil {
+		ctxObj.cmdPath = append(ctxObj.cmdPath, subCmd.cmdName)
+	} else if subCmdName != "" {
+		ctxObj.cmdPath = append(ctxObj.cmdPath, subCmdName)
 	}
 	if subCmd == nil {
+		if path := c.lookupPluginLocked(subCmdName); path != "" {
+			return filters, newPluginAction(path, arguments), true
+		}
 		if c.app.notFound != nil {
-			return nil, c.app.notFound, cmdPath, c, false
+			return nil, c.app.notFound, false
 		}
 		ThrowStatus(
 			StatusNotFound,
 			"",
-			fmt.Sprintf("not found command action: %q", strings.Join(cmdPath, " ")),
+			fmt.Sprintf("not found command action: %q", ctxObj.CmdPathString()),
 		)
-		return nil, nil, cmdPath, c, false
+		return nil, nil, false
+	}
+	if subCmd.deprecated != "" {
+		c.app.warnOnce(ctxObj, subCmd.PathString(), "command %q is deprecated: %s", subCmd.PathString(), subCmd.deprecated)
 	}
-	subFilters, action, cmdPath, subCmd2, found := subCmd.findFiltersAndAction(cmdPath, arguments, execScope)
+	subFilters, action, found := subCmd.findFiltersAndAction(ctxObj, arguments)
 	if found {
 		filters = append(filters, subFilters...)
-		return filters, action, cmdPath, subCmd2, true
+		return filters, action, true
 	}
-	return nil, action, cmdPath, subCmd2, false
+	return nil, action, false
 }
 
-func (c *Command) newFilters(arguments []string) (r []Filter, args []string) {
-	r = make([]Filter, len(c.filters))
+func (c *Command) newFilters(ctxObj *Context, arguments []string) (r []interface{}, args []string) {
+	r = make([]interface{}, len(c.filters))
 	args = arguments
 	for i, filter := range c.filters {
 		if filter.filterFunc != nil {
 			r[i] = filter.filterFunc
 		} else {
-			flagSet := NewFlagSet(c.cmdName, filter.flagSet.ErrorHandling())
-			newObj := filter.factory.DeepCopy()
+			flagSet := ctxObj.newFlagSet(c.cmdName, filter.flagSet.ErrorHandling())
+			newObj := filter.newObject()
 			flagSet.StructVars(newObj)
 			err := flagSet.Parse(arguments)
 			CheckStatus(err, StatusParseFailed, "")
+			c.warnDeprecatedFlags(ctxObj, flagSet)
+			err = c.app.inject(newObj)
+			CheckStatus(err, StatusInjectFailed, "")
 			if c.app.validator != nil {
 				err = c.app.validator(newObj)
 			}
@@ -285,29 +536,46 @@ func (c *Command) newFilters(arguments []string) (r []Filter, args []string) {
 			}
 		}
 	}
+	if ctxObj.applied == nil {
+		ctxObj.applied = make(map[*Command][]interface{})
+	}
+	ctxObj.applied[c] = r
 	return r, args
 }
 
-func (c *Command) newAction(cmdline []string) (Action, []string, bool) {
+func (c *Command) newAction(ctxObj *Context, cmdline []string) (interface{}, []string, bool) {
 	a := c.action
 	if a == nil {
 		return nil, cmdline, false
 	}
 	cmdName := a.flagSet.Name()
 	if a.actionFunc != nil {
-		_, cmdline = SplitArgs(cmdline)
+		// the function action parses no arguments, so all of them are left
 		return a.actionFunc, cmdline, true
 	}
-	flagSet := NewFlagSet(cmdName, a.flagSet.ErrorHandling())
-	newObj := a.actionFactory.DeepCopy()
+	flagSet := ctxObj.newFlagSet(cmdName, a.flagSet.ErrorHandling())
+	newObj := a.newObject()
 	flagSet.StructVars(newObj)
 	err := flagSet.Parse(cmdline)
 	CheckStatus(err, StatusParseFailed, "")
+	c.warnDeprecatedFlags(ctxObj, flagSet)
+	err = c.app.inject(newObj)
+	CheckStatus(err, StatusInjectFailed, "")
 	if a.cmd.app.validator != nil {
 		err = a.cmd.app.validator(newObj)
 	}
 	CheckStatus(err, StatusValidateFailed, "")
-	return newObj.(Action), flagSet.NextArgs(), true
+	return newObj, flagSet.NextArgs(), true
+}
+
+// warnDeprecatedFlags warns once for each deprecated flag or non-flag provided.
+func (c *Command) warnDeprecatedFlags(ctxObj *Context, flagSet *FlagSet) {
+	flagSet.Range(func(f *Flag) {
+		if msg := flagSet.Deprecated(f.Name); msg != "" {
+			name := displayFlagName(f.Name)
+			c.app.warnOnce(ctxObj, c.PathString()+" "+name, "%s of %q is deprecated: %s", name, c.PathString(), msg)
+		}
+	})
 }
 
 // CmdName returns the command name of the command.
@@ -363,7 +631,7 @@ func (c *Command) LookupSubcommand(pathCmdNames ...string) *Command {
 		if name == "" {
 			continue
 		}
-		r = r.subcommands[name]
+		r = r.lookupSubcommandLocked(name)
 		if r == nil {
 			return nil
 		}
@@ -371,6 +639,48 @@ func (c *Command) LookupSubcommand(pathCmdNames ...string) *Command {
 	return r
 }
 
+// lookupSubcommandLocked returns the subcommand by the name or alias,
+// returning nil if none exists.
+func (c *Command) lookupSubcommandLocked(name string) *Command {
+	if subCmd := c.subcommands[name]; subCmd != nil {
+		return subCmd
+	}
+	return c.aliasSubcommands[name]
+}
+
+// matchSubcommandLocked returns the subcommand by the name, alias,
+// or unique prefix if the prefix matching is enabled.
+// NOTE:
+//  returns nil if none exists;
+//  panic a not-found status when the prefix is ambiguous.
+func (c *Command) matchSubcommandLocked(name string) *Command {
+	if subCmd := c.lookupSubcommandLocked(name); subCmd != nil || name == "" || !c.app.prefixMatching {
+		return subCmd
+	}
+	var candidates []*Command
+	for _, subCmd := range c.Subcommands() {
+		if !subCmd.hidden && strings.HasPrefix(subCmd.cmdName, name) {
+			candidates = append(candidates, subCmd)
+		}
+	}
+	switch len(candidates) {
+	case 0:
+		return nil
+	case 1:
+		return candidates[0]
+	}
+	names := make([]string, len(candidates))
+	for i, subCmd := range candidates {
+		names[i] = subCmd.cmdName
+	}
+	ThrowStatus(
+		StatusNotFound,
+		"",
+		fmt.Sprintf("ambiguous command %q, candidates: %s", strings.Join(append(c.Path(), name), " "), strings.Join(names, ", ")),
+	)
+	return nil
+}
+
 // Subcommands returns the subcommands.
 func (c *Command) Subcommands() []*Command {
 	names := make([]string, 0, len(c.subcommands))
@@ -387,19 +697,39 @@ func (c *Command) Subcommands() []*Command {
 
 // FindActionCommands finds list of action commands by the executor scope.
 // NOTE:
-//  if @scopes is empty, all action commands are returned.
+//  if @scopes is empty, all action commands are returned;
+//  the hidden commands and the ones under them are not returned.
 func (c *Command) FindActionCommands(execScope ...Scope) []*Command {
 	c.lock.Lock()
 	defer c.lock.Unlock()
 	fn := c.app.scopeMatcherFunc
 	if fn == nil || len(execScope) == 0 {
-		return c.scopeCommands
+		return c.visibleCommands(c.scopeCommands)
 	}
 	scope := execScope[0]
 	list := make([]*Command, 0, len(c.scopeCommands))
 	for s, sc := range c.scopeCommandMap {
 		if fn(s, scope) == nil {
-			list = append(list, sc...)
+			list = append(list, c.visibleCommands(sc)...)
+		}
+	}
+	return list
+}
+
+// visibleCommands returns the commands that are neither hidden nor under
+// a hidden command below @c.
+func (c *Command) visibleCommands(cmds []*Command) []*Command {
+	list := make([]*Command, 0, len(cmds))
+	for _, cmd := range cmds {
+		hidden := false
+		for p := cmd; p != nil && p != c; p = p.parent {
+			if p.hidden {
+				hidden = true
+				break
+			}
+		}
+		if !hidden {
+			list = append(list, cmd)
 		}
 	}
 	return list
@@ -444,15 +774,7 @@ func (c *Command) UsageText(execScope ...Scope) string {
 	if ok {
 		return txt
 	}
-	m := make(map[*Command]bool, len(c.scopeCommands))
-	for s, sc := range c.scopeCommandMap {
-		if fn(s, scope) == nil {
-			for _, cmd := range sc {
-				m[cmd] = true
-			}
-		}
-	}
-	txt = c.createUsageLocked(m)
+	txt = c.renderUsageLocked(c.inScopeFunc(scope), "")
 	if c.execScopeUsageTexts == nil {
 		c.execScopeUsageTexts = make(map[Scope]string, 16)
 	}
@@ -460,61 +782,31 @@ func (c *Command) UsageText(execScope ...Scope) string {
 	return txt
 }
 
-func (c *Command) updateUsageLocked() {
-	c.usageText = c.newUsageLocked()
-	subcommands := c.Subcommands()
-	for _, subCmd := range subcommands {
-		subCmd.updateUsageLocked()
-		if subCmd.parentUsageVisible {
-			c.usageText += subCmd.usageText
+// inScopeFunc returns the function reporting whether the command
+// is visible to the executor scope.
+func (c *Command) inScopeFunc(execScope Scope) func(*Command) bool {
+	fn := c.app.scopeMatcherFunc
+	m := make(map[*Command]bool, len(c.scopeCommands))
+	for s, sc := range c.scopeCommandMap {
+		if fn(s, execScope) == nil {
+			for _, cmd := range sc {
+				m[cmd] = true
+			}
 		}
 	}
-}
-
-func (c *Command) createUsageLocked(m map[*Command]bool) string {
-	if !m[c] {
-		return ""
+	return func(cmd *Command) bool {
+		return m[cmd]
 	}
-	usageText := c.newUsageLocked()
-	for _, subCmd := range c.Subcommands() {
-		if subCmd.parentUsageVisible {
-			usageText += subCmd.createUsageLocked(m)
-		}
-	}
-	return usageText
 }
 
-func (c *Command) newUsageLocked() (text string) {
-	var buf bytes.Buffer
-	flags := make([]*Flag, 0, len(c.filters)+1)
-	for _, filter := range c.filters {
-		filter.flagSet.RangeAll(func(f *Flag) {
-			flags = append(flags, f)
-		})
-	}
-	if c.action != nil {
-		c.action.flagSet.RangeAll(func(f *Flag) {
-			flags = append(flags, f)
-		})
-	}
-	fn := newPrintOneDefault(&buf, true)
-	for _, f := range flags {
-		fn(f)
+func (c *Command) updateUsageLocked() {
+	c.usageText = c.renderUsageLocked(nil, "")
+	c.execScopeUsageTextsLock.Lock()
+	c.execScopeUsageTexts = nil
+	c.execScopeUsageTextsLock.Unlock()
+	for _, subCmd := range c.Subcommands() {
+		subCmd.updateUsageLocked()
 	}
-	body := buf.String()
-	if c.parent != nil { // non-global command
-		var ellipsis string
-		if c.action == nil {
-			ellipsis = " ..."
-		}
-		text = fmt.Sprintf("$%s%s\n  %s\n", c.PathString(), ellipsis, c.description)
-	} else {
-		body = strings.Replace(body, "  -", "-", -1)
-		body = strings.Replace(body, "\n    \t", "\n  \t", -1)
-	}
-	body = strings.Replace(body, "-?", "?", -1)
-	text += body
-	return text
 }
 
 type commandList []*Command
@@ -0,0 +1,148 @@
+package flagx
+
+import (
+	"reflect"
+
+	"github.com/henrylee2cn/ameda"
+)
+
+// CopyMode how the default factory copies the prototype of the struct action or filter
+// for each execution.
+type CopyMode int8
+
+const (
+	// CopyShallow copies the prototype struct by value, so that the pointers,
+	// maps, slices, channels, functions and interfaces in it are shared.
+	CopyShallow CopyMode = iota
+	// CopyDeep copies the prototype struct recursively, including the values
+	// referenced by the exported pointers, maps, slices and interfaces.
+	// NOTE:
+	//  the unexported fields, channels and functions are still shared.
+	CopyDeep
+	// CopyZero ignores the prototype and creates the zero value of the struct.
+	CopyZero
+)
+
+// SetCopyMode sets how the default factory copies the prototype of the struct action or filter.
+// NOTE:
+//  defaults to CopyShallow;
+//  it applies to the actions and filters set after it is called;
+//  the flag fields are always reset to their default values after copying.
+func (a *App) SetCopyMode(mode CopyMode) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.copyMode = mode
+}
+
+// SetActionFactory sets the action of the command, which is created by @fn
+// for each execution.
+// NOTE:
+//  @fn must return a pointer to a new struct action;
+//  panic when something goes wrong.
+func (c *Command) SetActionFactory(fn func() Action, scope ...Scope) {
+	c.setAction(unwrapAction(fn()), actionFactoryFunc(fn), scope...)
+}
+
+// actionFactoryFunc an action copier created by the function.
+type actionFactoryFunc func() Action
+
+// DeepCopy implements ActionCopier interface.
+func (fn actionFactoryFunc) DeepCopy() Action {
+	return fn()
+}
+
+// newActionFactory returns the default factory of the struct action or filter.
+// NOTE:
+//  the nil pointer prototype is treated as the zero value.
+func (a *App) newActionFactory(elemType reflect.Type, prototype interface{}) *actionFactory {
+	proto := ameda.DereferenceValue(reflect.ValueOf(prototype))
+	if !proto.IsValid() {
+		proto = reflect.Zero(elemType)
+	}
+	return &actionFactory{elemType: elemType, proto: proto, mode: a.copyMode}
+}
+
+// newObject returns a new object copied from the prototype.
+func (h *actionFactory) newObject() interface{} {
+	obj := reflect.New(h.elemType)
+	switch h.mode {
+	case CopyZero:
+	case CopyDeep:
+		obj.Elem().Set(deepCopyValue(h.proto, make(map[copiedPointer]reflect.Value)))
+	default:
+		obj.Elem().Set(h.proto)
+	}
+	return obj.Interface()
+}
+
+// copiedPointer the key of a pointer copied by deepCopyValue.
+// NOTE:
+//  the type is part of the key, since a pointer to a struct and a pointer
+//  to its first field have the same address.
+type copiedPointer struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// deepCopyValue returns a copy of the value, copying the values it references recursively.
+// NOTE:
+//  @seen maps the pointers copied to their copies, to keep the cycles.
+func deepCopyValue(v reflect.Value, seen map[copiedPointer]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v
+		}
+		key := copiedPointer{addr: v.Pointer(), typ: v.Type()}
+		if p, ok := seen[key]; ok {
+			return p
+		}
+		p := reflect.New(v.Type().Elem())
+		seen[key] = p
+		p.Elem().Set(deepCopyValue(v.Elem(), seen))
+		return p
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(deepCopyValue(v.Elem(), seen))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if f := c.Field(i); f.CanSet() {
+				f.Set(deepCopyValue(v.Field(i), seen))
+			}
+		}
+		return c
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(deepCopyValue(v.Index(i), seen))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(deepCopyValue(iter.Key(), seen), deepCopyValue(iter.Value(), seen))
+		}
+		return c
+	default:
+		return v
+	}
+}
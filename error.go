@@ -0,0 +1,146 @@
+package flagx
+
+import (
+	"errors"
+)
+
+// StatusError an error that carries the status, which can be returned by
+// ActionE and FilterE to fail with the status.
+type StatusError struct {
+	Status *Status
+}
+
+// NewStatusError returns an error that carries the status.
+func NewStatusError(stat *Status) error {
+	return &StatusError{Status: stat}
+}
+
+// Error implements error interface.
+func (e *StatusError) Error() string {
+	return e.Status.Msg()
+}
+
+// Unwrap returns the cause of the status.
+func (e *StatusError) Unwrap() error {
+	return e.Status.Cause()
+}
+
+// ErrorCodeFunc returns the status code of the error returned by ActionE or FilterE.
+type ErrorCodeFunc func(err error) int32
+
+// SetErrorCode sets the function that returns the status code of the error
+// returned by ActionE or FilterE.
+// NOTE:
+//  if an error in the chain is *StatusError, its status is used as is;
+//  defaults to StatusActionFailed.
+func (a *App) SetErrorCode(fn ErrorCodeFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.errorCode = fn
+}
+
+// errorStatus converts the error into a status with stack.
+func (a *App) errorStatus(err error) *Status {
+	var statErr *StatusError
+	if errors.As(err, &statErr) && statErr.Status != nil {
+		return statErr.Status
+	}
+	a.lock.RLock()
+	fn := a.errorCode
+	a.lock.RUnlock()
+	code := StatusActionFailed
+	if fn != nil {
+		code = fn(err)
+	}
+	return NewStatus(code, "", err).TagStack(2)
+}
+
+type (
+	// actionE the Action that executes ActionE, see WrapActionE.
+	actionE struct {
+		action ActionE
+	}
+	// filterE the Filter that executes FilterE, see WrapFilterE.
+	filterE struct {
+		filter FilterE
+	}
+)
+
+// WrapActionE returns the Action that executes the ActionE, whose error is
+// converted into a status, such as c.SetAction(flagx.WrapActionE(new(Deploy))).
+// NOTE:
+//  if action is a struct, it is still the prototype parsed for each execution,
+//  and Context.Action returns the parsed ActionE.
+func WrapActionE(action ActionE) Action {
+	return &actionE{action: action}
+}
+
+// Execute implements Action interface.
+func (a *actionE) Execute(c *Context) {
+	if err := a.action.Execute(c); err != nil {
+		panic(c.cmd.app.errorStatus(err))
+	}
+}
+
+// WrapFilterE returns the Filter that executes the FilterE, whose error is
+// converted into a status, see WrapActionE.
+func WrapFilterE(filter FilterE) Filter {
+	return &filterE{filter: filter}
+}
+
+// Filter implements Filter interface.
+func (f *filterE) Filter(c *Context, next ActionFunc) {
+	if err := f.filter.Filter(c, next); err != nil {
+		panic(c.cmd.app.errorStatus(err))
+	}
+}
+
+// unwrapAction returns the ActionE wrapped by WrapActionE, or the action itself.
+func unwrapAction(action Action) interface{} {
+	if a, ok := action.(*actionE); ok {
+		return a.action
+	}
+	return action
+}
+
+// unwrapFilter returns the FilterE wrapped by WrapFilterE, or the filter itself.
+func unwrapFilter(filter Filter) interface{} {
+	if f, ok := filter.(*filterE); ok {
+		return f.filter
+	}
+	return filter
+}
+
+// toActionFunc converts Action or ActionE into ActionFunc.
+func (a *App) toActionFunc(action interface{}) ActionFunc {
+	switch v := action.(type) {
+	case ActionFunc:
+		return v
+	case Action:
+		return v.Execute
+	case ActionE:
+		return func(c *Context) {
+			if err := v.Execute(c); err != nil {
+				panic(a.errorStatus(err))
+			}
+		}
+	}
+	return nil
+}
+
+// toFilterFunc converts Filter or FilterE into FilterFunc.
+func (a *App) toFilterFunc(filter interface{}) FilterFunc {
+	switch v := filter.(type) {
+	case FilterFunc:
+		return v
+	case Filter:
+		return v.Filter
+	case FilterE:
+		return func(c *Context, next ActionFunc) {
+			if err := v.Filter(c, next); err != nil {
+				panic(a.errorStatus(err))
+			}
+		}
+	}
+	return nil
+}
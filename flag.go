@@ -28,6 +28,16 @@ type (
 		terminated            bool
 		nonActual             map[int]*Flag
 		nonFormal             map[int]*Flag
+		extras                map[string]*flagExtra
+		lookupEnv             func(key string) (string, bool)
+	}
+
+	// flagExtra the extra definition of a flag or non-flag.
+	flagExtra struct {
+		required   bool
+		env        string
+		hidden     bool
+		deprecated string
 	}
 
 	// A Flag represents the state of a flag.
@@ -246,7 +256,7 @@ func (f *FlagSet) NonVar(value Value, index int, usage string) {
 	}
 	name := getNonFlagName(index)
 	// Remember the default value as a string; it won't change.
-	flag := &Flag{name, usage, value, value.String()}
+	flag := &Flag{Name: name, Usage: usage, Value: value, DefValue: value.String()}
 	_, alreadythere := f.nonFormal[index]
 	if alreadythere {
 		var msg string
@@ -268,7 +278,32 @@ func (f *FlagSet) NonVar(value Value, index int, usage string) {
 // include the command name. Must be called after all flags in the FlagSet
 // are defined and before flags are accessed by the program.
 // The return value will be ErrHelp if -help or -h were set but not defined.
+// NOTE:
+//  unlike the standard flag package, Parse also sets the flags and non-flags that
+//  are not provided from the environment variables bound by BindEnv or the `env=` tag,
+//  then fails if any one marked by SetRequired or the `required` tag is still missing;
+//  such a failure is handled by the error handling of the FlagSet, e.g. ExitOnError
+//  exits with code 2.
 func (f *FlagSet) Parse(arguments []string) error {
+	err := f.parse(arguments)
+	if err != nil {
+		return err
+	}
+	err = f.parseEnvAndRequired()
+	if err != nil {
+		switch f.FlagSet.ErrorHandling() {
+		case ContinueOnError:
+			return err
+		case ExitOnError:
+			os.Exit(2)
+		case PanicOnError:
+			panic(err)
+		}
+	}
+	return nil
+}
+
+func (f *FlagSet) parse(arguments []string) error {
 	if f.isContinueOnUndefined {
 		flagArgs, nonFlagArgs, terminated, err := tidyArgs(arguments, func(name string) (want, next bool) {
 			return f.FlagSet.Lookup(name) != nil, true
@@ -327,6 +362,157 @@ func (f *FlagSet) Parse(arguments []string) error {
 	return nil
 }
 
+// parseEnvAndRequired sets the flags and non-flags that are not provided
+// from the bound environment variables, and checks the required ones.
+func (f *FlagSet) parseEnvAndRequired() error {
+	if len(f.extras) == 0 {
+		return nil
+	}
+	actual := make(map[string]bool, len(f.extras))
+	f.Range(func(flag *Flag) {
+		actual[flag.Name] = true
+	})
+	names := make([]string, 0, len(f.extras))
+	for name := range f.extras {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if actual[name] {
+			continue
+		}
+		extra := f.extras[name]
+		if extra.env != "" {
+			if value, ok := f.lookupEnvFunc()(extra.env); ok {
+				if err := f.Set(name, value); err != nil {
+					return f.failf("invalid value %q of $%s for %s: %v", value, extra.env, displayFlagName(name), err)
+				}
+				continue
+			}
+		}
+		if extra.required {
+			return f.failf("required but not provided: %s", displayFlagName(name))
+		}
+	}
+	return nil
+}
+
+// SetRequired marks the flag or non-flag as required,
+// so that Parse fails if it is not provided.
+func (f *FlagSet) SetRequired(name string) error {
+	extra, err := f.extra(name)
+	if err != nil {
+		return err
+	}
+	extra.required = true
+	return nil
+}
+
+// Required reports whether the flag or non-flag is required.
+func (f *FlagSet) Required(name string) bool {
+	extra := f.extras[name]
+	return extra != nil && extra.required
+}
+
+// BindEnv binds the flag or non-flag to the environment variable,
+// whose value is used if it is not provided in the arguments.
+func (f *FlagSet) BindEnv(name, key string) error {
+	extra, err := f.extra(name)
+	if err != nil {
+		return err
+	}
+	extra.env = key
+	return nil
+}
+
+// EnvKey returns the environment variable bound to the flag or non-flag.
+func (f *FlagSet) EnvKey(name string) string {
+	extra := f.extras[name]
+	if extra == nil {
+		return ""
+	}
+	return extra.env
+}
+
+// SetEnvLookup sets the function to look up the environment variables
+// bound to the flags and non-flags.
+// NOTE:
+//  if fn is nil, os.LookupEnv is used.
+func (f *FlagSet) SetEnvLookup(fn func(key string) (string, bool)) {
+	f.lookupEnv = fn
+}
+
+func (f *FlagSet) lookupEnvFunc() func(key string) (string, bool) {
+	if f.lookupEnv == nil {
+		return os.LookupEnv
+	}
+	return f.lookupEnv
+}
+
+// SetHidden hides the flag or non-flag from the usage.
+func (f *FlagSet) SetHidden(name string) error {
+	extra, err := f.extra(name)
+	if err != nil {
+		return err
+	}
+	extra.hidden = true
+	return nil
+}
+
+// Hidden reports whether the flag or non-flag is hidden from the usage.
+func (f *FlagSet) Hidden(name string) bool {
+	extra := f.extras[name]
+	return extra != nil && extra.hidden
+}
+
+// SetDeprecated marks the flag or non-flag as deprecated,
+// the message is shown in the usage and the warning when it is provided.
+func (f *FlagSet) SetDeprecated(name, message string) error {
+	if message == "" {
+		return fmt.Errorf("empty deprecation message of %s", displayFlagName(name))
+	}
+	extra, err := f.extra(name)
+	if err != nil {
+		return err
+	}
+	extra.deprecated = message
+	return nil
+}
+
+// Deprecated returns the deprecation message of the flag or non-flag,
+// returning empty if it is not deprecated.
+func (f *FlagSet) Deprecated(name string) string {
+	extra := f.extras[name]
+	if extra == nil {
+		return ""
+	}
+	return extra.deprecated
+}
+
+func (f *FlagSet) extra(name string) (*flagExtra, error) {
+	if f.Lookup(name) == nil {
+		return nil, fmt.Errorf("no such flag %s", displayFlagName(name))
+	}
+	extra := f.extras[name]
+	if extra == nil {
+		if f.extras == nil {
+			f.extras = make(map[string]*flagExtra)
+		}
+		extra = new(flagExtra)
+		f.extras[name] = extra
+	}
+	return extra, nil
+}
+
+// displayFlagName returns the name of the flag or non-flag
+// as it appears in the usage, such as `-id` or `?0`.
+func displayFlagName(name string) string {
+	if strings.HasPrefix(name, tagKeyNonFlag) {
+		return name
+	}
+	return "-" + name
+}
+
 // parseOneNonFlag parses one non-flag. It reports whether a non-flag was seen.
 func (f *FlagSet) parseOneNonFlag(index int, value string) (bool, error) {
 	if value == "--" {
@@ -3,6 +3,7 @@ package flagx
 import (
 	"fmt"
 	"os"
+	"os/exec"
 	"strconv"
 	"testing"
 	"time"
@@ -52,7 +53,7 @@ func ExampleStructVars() {
 	// {Run:^(TestStructVars)$ Timeout:30s V:true X:10 Y:flag_test.go}
 }
 
-func ExampleMoreStructVars() {
+func ExampleFlagSet_StructVars() {
 	type Anonymous struct {
 		F    float64 `flag:"f"`
 		Non3 int     `flag:"?3"`
@@ -174,3 +175,91 @@ func TestNonVar(t *testing.T) {
 	assert.Equal(t, "abc", *runVal)
 	fs.Usage()
 }
+
+func TestParseEnvAndRequired(t *testing.T) {
+	os.Unsetenv("FLAGX_TEST_X")
+	os.Unsetenv("FLAGX_TEST_N")
+	defer os.Unsetenv("FLAGX_TEST_X")
+	defer os.Unsetenv("FLAGX_TEST_N")
+	var x string
+	var n int
+	newFlagSet := func(errorHandling ErrorHandling) *FlagSet {
+		fs := NewFlagSet("test", errorHandling)
+		fs.StringVar(&x, "x", "", "")
+		fs.NonVar(newIntValue(0, &n), 0, "")
+		assert.NoError(t, fs.SetRequired("x"))
+		assert.NoError(t, fs.BindEnv("x", "FLAGX_TEST_X"))
+		assert.NoError(t, fs.BindEnv("?0", "FLAGX_TEST_N"))
+		return fs
+	}
+
+	fs := newFlagSet(ContinueOnError)
+	assert.EqualError(t, fs.BindEnv("y", "FLAGX_TEST_Y"), "no such flag -y")
+	assert.True(t, fs.Required("x"))
+	assert.False(t, fs.Required("?0"))
+	assert.Equal(t, "FLAGX_TEST_X", fs.EnvKey("x"))
+	assert.EqualError(t, fs.Parse(nil), "required but not provided: -x")
+
+	fs = newFlagSet(ContinueOnError)
+	assert.NoError(t, fs.Parse([]string{"-x", "a", "1"}))
+	assert.Equal(t, "a", x)
+	assert.Equal(t, 1, n)
+
+	os.Setenv("FLAGX_TEST_X", "b")
+	os.Setenv("FLAGX_TEST_N", "2")
+	fs = newFlagSet(ContinueOnError)
+	assert.NoError(t, fs.Parse(nil))
+	assert.Equal(t, "b", x)
+	assert.Equal(t, 2, n)
+
+	fs = newFlagSet(ContinueOnError)
+	assert.NoError(t, fs.Parse([]string{"-x", "a", "1"}))
+	assert.Equal(t, "a", x)
+	assert.Equal(t, 1, n)
+
+	os.Setenv("FLAGX_TEST_N", "two")
+	fs = newFlagSet(ContinueOnError)
+	assert.EqualError(t, fs.Parse(nil), `invalid value "two" of $FLAGX_TEST_N for ?0: parse error`)
+
+	os.Unsetenv("FLAGX_TEST_X")
+	os.Unsetenv("FLAGX_TEST_N")
+	fs = newFlagSet(PanicOnError)
+	assert.PanicsWithError(t, "required but not provided: -x", func() {
+		fs.Parse(nil)
+	})
+}
+
+func TestParseRequiredExitOnError(t *testing.T) {
+	if os.Getenv("FLAGX_TEST_EXIT") == "1" {
+		fs := NewFlagSet("test", ExitOnError)
+		fs.String("x", "", "")
+		fs.SetRequired("x")
+		fs.Parse(nil)
+		return
+	}
+	cmd := exec.Command(os.Args[0], "-test.run=^TestParseRequiredExitOnError$")
+	cmd.Env = append(os.Environ(), "FLAGX_TEST_EXIT=1")
+	err := cmd.Run()
+	exitErr, ok := err.(*exec.ExitError)
+	if assert.True(t, ok, err) {
+		assert.Equal(t, 2, exitErr.ExitCode())
+	}
+}
+
+func TestStructVarsUsageWithSemicolon(t *testing.T) {
+	type Args struct {
+		ID    int    `flag:"usage=the id;id"`
+		Level int    `flag:"usage=log level; 0 is quiet;level,l;def=1"`
+		Mode  string `flag:"mode;usage=a;b"`
+		Path  string `flag:"usage=the path;?0"`
+	}
+	fs := NewFlagSet("test", ContinueOnError)
+	assert.NoError(t, fs.StructVars(new(Args)))
+	assert.Nil(t, fs.Lookup("ID"))
+	assert.Equal(t, "the id", fs.Lookup("id").Usage)
+	assert.Equal(t, "log level; 0 is quiet", fs.Lookup("level").Usage)
+	assert.Equal(t, "log level; 0 is quiet", fs.Lookup("l").Usage)
+	assert.Equal(t, "1", fs.Lookup("level").DefValue)
+	assert.Equal(t, "a;b", fs.Lookup("mode").Usage)
+	assert.Equal(t, "the path", fs.Lookup("?0").Usage)
+}
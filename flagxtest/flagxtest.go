@@ -0,0 +1,154 @@
+// Package flagxtest provides utilities for testing the flagx applications.
+package flagxtest
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/henrylee2cn/flagx"
+)
+
+// update rewrites the golden files with the actual usage, such as `go test -args -flagxtest.update`.
+var update = flag.Bool("flagxtest.update", false, "update the golden files of flagxtest")
+
+// stubs the commands stubbed by Stub, with the number of the stubs.
+var stubs = struct {
+	sync.Mutex
+	m map[*flagx.Command]int
+}{m: make(map[*flagx.Command]int)}
+
+type (
+	// Result the result of an execution driven by Run.
+	Result struct {
+		Status  *flagx.Status  // the status of the execution
+		Code    int            // the exit code of the status, see App.ExitCode
+		Stdout  string         // the standard output of the execution
+		Stderr  string         // the standard error, including the flag parsing messages and warnings
+		CmdPath []string       // the command path reached, even if the execution fails
+		Action  interface{}    // the parsed action object, nil if the action is not found
+		Context *flagx.Context // the context of the execution, nil if the action is stubbed
+	}
+	// UsageTexter the App or Command whose usage is asserted.
+	UsageTexter interface {
+		UsageText(execScope ...flagx.Scope) string
+	}
+)
+
+// Run executes the app with the arguments, whose standard input is empty,
+// and returns the result with the output captured.
+// NOTE:
+//  the arguments do not contain the program name.
+func Run(t testing.TB, app *flagx.App, args ...string) *Result {
+	t.Helper()
+	return RunScope(t, app, flagx.InitialScope, args...)
+}
+
+// RunScope executes the app with the arguments by the executor scope, see Run.
+func RunScope(t testing.TB, app *flagx.App, execScope flagx.Scope, args ...string) *Result {
+	t.Helper()
+	if r, stat := app.Resolve(args, execScope); stat.OK() && isStubbed(r.Command) {
+		return &Result{
+			Status:  stat,
+			Code:    app.ExitCode(stat),
+			CmdPath: r.CmdPath,
+			Action:  r.Action,
+		}
+	}
+	var stdout, stderr bytes.Buffer
+	ctx := flagx.WithIO(context.Background(), strings.NewReader(""), &stdout, &stderr)
+	ctxObj, stat := app.ExecContext(ctx, args, execScope)
+	return &Result{
+		Status:  stat,
+		Code:    app.ExitCode(stat),
+		Stdout:  stdout.String(),
+		Stderr:  stderr.String(),
+		CmdPath: ctxObj.CmdPath(),
+		Action:  ctxObj.Action(),
+		Context: ctxObj,
+	}
+}
+
+// OK reports whether the status of the execution is OK.
+func (r *Result) OK() bool {
+	return r.Status.OK()
+}
+
+// CmdPathString returns the command path string reached.
+func (r *Result) CmdPathString() string {
+	return strings.Join(r.CmdPath, " ")
+}
+
+// Stub stubs the action of the command by the path under the app for Run and RunScope,
+// so that the arguments are routed and parsed, but nothing is executed,
+// and returns the function that restores it.
+// NOTE:
+//  the app is not changed, and the command line reaching the command is resolved
+//  by App.Resolve, so the hooks and filters are not executed either,
+//  and the Context of the result is nil;
+//  the command line that fails to resolve is still executed to report the failure;
+//  panic when the command does not exist or has no action.
+func Stub(app *flagx.App, path ...string) (restore func()) {
+	cmd := app.LookupSubcommand(path...)
+	if cmd == nil {
+		panic(fmt.Errorf("flagxtest: not found command: %q", strings.Join(path, " ")))
+	}
+	if !cmd.HasAction() {
+		panic(fmt.Errorf("flagxtest: command has no action: %q", cmd.PathString()))
+	}
+	stubs.Lock()
+	stubs.m[cmd]++
+	stubs.Unlock()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			stubs.Lock()
+			defer stubs.Unlock()
+			if stubs.m[cmd]--; stubs.m[cmd] <= 0 {
+				delete(stubs.m, cmd)
+			}
+		})
+	}
+}
+
+func isStubbed(cmd *flagx.Command) bool {
+	stubs.Lock()
+	defer stubs.Unlock()
+	return stubs.m[cmd] > 0
+}
+
+// AssertUsage asserts that the usage text by the executor scope equals the content
+// of the golden file, and reports whether it is equal.
+// NOTE:
+//  the golden file is written with the usage text when the -flagxtest.update flag is set.
+func AssertUsage(t testing.TB, cmd UsageTexter, golden string, execScope ...flagx.Scope) bool {
+	t.Helper()
+	got := cmd.UsageText(execScope...)
+	if *update {
+		err := os.MkdirAll(filepath.Dir(golden), 0755)
+		if err == nil {
+			err = ioutil.WriteFile(golden, []byte(got), 0644)
+		}
+		if err != nil {
+			t.Fatalf("flagxtest: failed to update the golden file: %v", err)
+		}
+		return true
+	}
+	want, err := ioutil.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("flagxtest: failed to read the golden file, run with -flagxtest.update to create it: %v", err)
+		return false
+	}
+	if got != string(want) {
+		t.Errorf("flagxtest: usage text does not match the golden file %s\nwant:\n%s\ngot:\n%s", golden, want, got)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,74 @@
+package flagxtest_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/henrylee2cn/flagx"
+	"github.com/henrylee2cn/flagx/flagxtest"
+	"github.com/stretchr/testify/assert"
+)
+
+type Deploy struct {
+	Env string `flag:"env;usage=target environment;required"`
+}
+
+func (d *Deploy) Execute(c *flagx.Context) {
+	fmt.Fprintf(c.Stdout(), "deploying to %s\n", d.Env)
+}
+
+func newApp() *flagx.App {
+	app := flagx.NewApp()
+	app.SetCmdName("testapp")
+	app.AddSubaction("deploy", "deploy the service", new(Deploy))
+	app.AddSubaction("version", "print the version", flagx.ActionFunc(func(c *flagx.Context) {
+		fmt.Fprintln(c.Stdout(), "v1")
+	}))
+	return app
+}
+
+func TestRun(t *testing.T) {
+	app := newApp()
+	r := flagxtest.Run(t, app, "deploy", "-env=prod")
+	assert.True(t, r.OK(), r.Status)
+	assert.Equal(t, 0, r.Code)
+	assert.Equal(t, "deploying to prod\n", r.Stdout)
+	assert.Equal(t, "testapp deploy", r.CmdPathString())
+	assert.Equal(t, &Deploy{Env: "prod"}, r.Action)
+
+	r = flagxtest.Run(t, app, "deploy")
+	assert.Equal(t, flagx.StatusParseFailed, r.Status.Code())
+	assert.Equal(t, flagx.ExitCodeUsage, r.Code)
+	assert.Contains(t, r.Stderr, "required but not provided: -env")
+
+	r = flagxtest.Run(t, app, "undeploy")
+	assert.Equal(t, flagx.StatusNotFound, r.Status.Code())
+	assert.Equal(t, []string{"testapp", "undeploy"}, r.CmdPath)
+	assert.Nil(t, r.Action)
+}
+
+func TestStub(t *testing.T) {
+	app := newApp()
+	restore := flagxtest.Stub(app, "deploy")
+	r := flagxtest.Run(t, app, "deploy", "-env=prod")
+	assert.True(t, r.OK(), r.Status)
+	assert.Empty(t, r.Stdout)
+	assert.Equal(t, []string{"testapp", "deploy"}, r.CmdPath)
+	assert.Equal(t, &Deploy{Env: "prod"}, r.Action)
+	assert.Nil(t, r.Context)
+	r = flagxtest.Run(t, app, "deploy")
+	assert.Equal(t, flagx.StatusParseFailed, r.Status.Code())
+	assert.Contains(t, r.Stderr, "required but not provided: -env")
+	assert.Panics(t, func() { flagxtest.Stub(app, "undeploy") })
+
+	restore()
+	restore()
+	r = flagxtest.Run(t, app, "deploy", "-env=prod")
+	assert.True(t, r.OK(), r.Status)
+	assert.NotEmpty(t, r.Stdout)
+	assert.Empty(t, r.Context.Filters())
+}
+
+func TestAssertUsage(t *testing.T) {
+	flagxtest.AssertUsage(t, newApp(), "testdata/usage.golden")
+}
@@ -0,0 +1,142 @@
+package flagx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// helpCmdName the name of the built-in help command, e.g. `app help b c`.
+const helpCmdName = "help"
+
+// isHelpFlag reports whether the flag name requests the usage.
+func isHelpFlag(name string) bool {
+	return name == "h" || name == "help"
+}
+
+// execHelp prints the usage if the arguments request it.
+// NOTE:
+//  panic a not-found status when the command of `help` does not exist.
+func (c *Command) execHelp(ctxObj *Context, arguments []string, execScope []Scope) bool {
+	cmd, ok := c.findHelp(arguments)
+	if !ok {
+		return false
+	}
+	var text string
+	if cmd == c.app.Command {
+		text = c.app.UsageText(execScope...)
+	} else {
+		text = cmd.UsageText(execScope...)
+	}
+	fmt.Fprint(c.app.helpWriter(ctxObj), text)
+	return true
+}
+
+func (c *Command) findHelp(arguments []string) (*Command, bool) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.findHelpCommand(arguments, true)
+}
+
+// findHelpCommand finds the command whose usage is requested by
+// the `-h`/`--help` flag at any depth or the `help` command.
+func (c *Command) findHelpCommand(arguments []string, top bool) (*Command, bool) {
+	var help bool
+	nNonFlag := c.nFormalFilterNonFlag()
+	var seenNonFlag int
+	for i := 0; i < len(arguments); i++ {
+		s := arguments[i]
+		if s == "--" {
+			break
+		}
+		if name, hasValue, ok := parseFlagToken(s); ok {
+			f := c.lookupFormalFlag(name)
+			if f == nil && isHelpFlag(name) && !hasValue {
+				help = true
+				continue
+			}
+			if !hasValue && i+1 < len(arguments) && takesValue(f, arguments[i+1]) {
+				i++
+			}
+			continue
+		}
+		if top && seenNonFlag == 0 && s == helpCmdName && c.lookupSubcommandLocked(helpCmdName) == nil {
+			return c.lookupHelpTopic(arguments[i+1:]), true
+		}
+		if seenNonFlag < nNonFlag || c.action != nil {
+			seenNonFlag++
+			continue
+		}
+		subCmd := c.matchSubcommandLocked(s)
+		if subCmd == nil {
+			break
+		}
+		cmd, subHelp := subCmd.findHelpCommand(arguments[i+1:], false)
+		return cmd, help || subHelp
+	}
+	return c, help
+}
+
+// lookupHelpTopic returns the command of the `help` command arguments.
+func (c *Command) lookupHelpTopic(arguments []string) *Command {
+	var names []string
+	for _, s := range arguments {
+		if s == "--" {
+			break
+		}
+		if !strings.HasPrefix(s, "-") {
+			names = append(names, s)
+		}
+	}
+	cmd := c
+	for _, name := range names {
+		if cmd = cmd.matchSubcommandLocked(name); cmd == nil {
+			break
+		}
+	}
+	if cmd == nil {
+		ThrowStatus(
+			StatusNotFound,
+			"",
+			fmt.Sprintf("not found command: %q", strings.Join(append(c.Path(), names...), " ")),
+		)
+	}
+	return cmd
+}
+
+// lookupFormalFlag returns the flag of the filters or action,
+// returning nil if none exists.
+func (c *Command) lookupFormalFlag(name string) *Flag {
+	for _, filter := range c.filters {
+		if f := filter.flagSet.FlagSet.Lookup(name); f != nil {
+			return f
+		}
+	}
+	if c.action != nil {
+		return c.action.flagSet.FlagSet.Lookup(name)
+	}
+	return nil
+}
+
+// nFormalFilterNonFlag returns the number of non-flags consumed by the filters.
+func (c *Command) nFormalFilterNonFlag() int {
+	var max int
+	for _, filter := range c.filters {
+		if n := filter.flagSet.NFormalNonFlag(); max < n {
+			max = n
+		}
+	}
+	return max
+}
+
+// takesValue reports whether the next argument is the value of the flag.
+// NOTE:
+//  undefined flags are treated in the same way as ContinueOnUndefined.
+func takesValue(f *Flag, next string) bool {
+	if f != nil {
+		if b, ok := f.Value.(boolFlag); ok && b.IsBoolFlag() {
+			return false
+		}
+		return true
+	}
+	return len(next) == 0 || next[0] != '-'
+}
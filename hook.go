@@ -0,0 +1,52 @@
+package flagx
+
+type (
+	// BeforeFunc is called before the command line is routed.
+	// NOTE:
+	//  If need to abort the execution, use *Context.ThrowStatus or *Context.CheckStatus
+	BeforeFunc func(c *Context)
+	// ErrorFunc is called with the failed status of the execution,
+	// and returns the status that replaces it.
+	// NOTE:
+	//  returning nil means the failure is handled.
+	ErrorFunc func(c *Context, stat *Status) *Status
+	// AfterFunc is called with the final status of the execution.
+	AfterFunc func(c *Context, stat *Status)
+)
+
+// SetBefore sets the function called before the command line is routed.
+// NOTE:
+//  the hooks run in the order: Before, the version or help, the routing
+//  (the not-found action if the command cannot be found), the filters, the action,
+//  OnError if any of them fails, and After.
+func (a *App) SetBefore(fn BeforeFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.before = fn
+}
+
+// SetOnError sets the function called with the failed status of the execution,
+// including the not-found, parse and validation failures.
+// NOTE:
+//  the returned status replaces the failed one.
+func (a *App) SetOnError(fn ErrorFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.onError = fn
+}
+
+// SetAfter sets the function always called with the final status of the execution.
+// NOTE:
+//  the context holds the command path reached even if the routing fails;
+//  if the function panics, its status replaces the final one.
+func (a *App) SetAfter(fn AfterFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.after = fn
+}
+
+func (a *App) hooks() (BeforeFunc, ErrorFunc, AfterFunc) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.before, a.onError, a.after
+}
@@ -0,0 +1,199 @@
+package flagx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/henrylee2cn/ameda"
+)
+
+// tag key of the injected field, such as `flag:"inject"` by type or `flag:"inject=db"` by name.
+const tagKeyInject = "inject"
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// provider a service provided to the struct actions and filters.
+type provider struct {
+	typ     reflect.Type
+	value   reflect.Value
+	factory reflect.Value // func() T or func() (T, error)
+}
+
+// Provide registers the services injected into the struct actions and filters
+// by the field type.
+// NOTE:
+//  a service can be a value, or a factory function of the form func() T or
+//  func() (T, error), which is called for each injection;
+//  the field can be of an interface type that the service implements;
+//  the services must be provided before the actions and filters are set;
+//  panic when something goes wrong
+func (a *App) Provide(services ...interface{}) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	for _, service := range services {
+		p := newProvider(service)
+		if a.providers[p.typ] != nil {
+			panic(fmt.Errorf("flagx: service of type %s already exists", p.typ))
+		}
+		if a.providers == nil {
+			a.providers = make(map[reflect.Type]*provider)
+		}
+		a.providers[p.typ] = p
+	}
+}
+
+// ProvideNamed registers the service injected into the struct actions and filters
+// by the name, such as the field with `flag:"inject=db"`.
+// NOTE:
+//  see Provide for the service;
+//  panic when something goes wrong
+func (a *App) ProvideNamed(name string, service interface{}) {
+	if name == "" {
+		panic("service name is empty")
+	}
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.namedProviders[name] != nil {
+		panic(fmt.Errorf("flagx: service named %s already exists", name))
+	}
+	if a.namedProviders == nil {
+		a.namedProviders = make(map[string]*provider)
+	}
+	a.namedProviders[name] = newProvider(service)
+}
+
+func newProvider(service interface{}) *provider {
+	v := reflect.ValueOf(service)
+	if !v.IsValid() {
+		panic("flagx: service is nil")
+	}
+	t := v.Type()
+	if t.Kind() == reflect.Func && t.NumIn() == 0 &&
+		(t.NumOut() == 1 || t.NumOut() == 2 && t.Out(1) == errorType) {
+		return &provider{typ: t.Out(0), factory: v}
+	}
+	return &provider{typ: t, value: v}
+}
+
+// get returns the service.
+func (p *provider) get() (reflect.Value, error) {
+	if !p.factory.IsValid() {
+		return p.value, nil
+	}
+	out := p.factory.Call(nil)
+	if len(out) == 2 && !out[1].IsNil() {
+		return reflect.Value{}, out[1].Interface().(error)
+	}
+	return out[0], nil
+}
+
+// lookupProvider returns the provider of the field.
+func (a *App) lookupProvider(name string, typ reflect.Type) (*provider, error) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if name != "" {
+		p := a.namedProviders[name]
+		if p == nil {
+			return nil, fmt.Errorf("flagx: no service named %s", name)
+		}
+		if !p.typ.AssignableTo(typ) {
+			return nil, fmt.Errorf("flagx: service named %s of type %s is not assignable to %s", name, p.typ, typ)
+		}
+		return p, nil
+	}
+	if p := a.providers[typ]; p != nil {
+		return p, nil
+	}
+	var r *provider
+	for t, p := range a.providers {
+		if t.AssignableTo(typ) {
+			if r != nil {
+				return nil, fmt.Errorf("flagx: ambiguous services of type %s and %s for %s", r.typ, t, typ)
+			}
+			r = p
+		}
+	}
+	if r == nil {
+		return nil, fmt.Errorf("flagx: no service of type %s", typ)
+	}
+	return r, nil
+}
+
+// checkInjection checks whether the injected fields of the struct can be resolved.
+func (a *App) checkInjection(t reflect.Type) error {
+	return a.rangeInjection(t, func(_ []int, name string, typ reflect.Type) error {
+		_, err := a.lookupProvider(name, typ)
+		return err
+	})
+}
+
+// inject sets the injected fields of the struct object.
+func (a *App) inject(obj interface{}) error {
+	v := ameda.DereferenceValue(reflect.ValueOf(obj))
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	return a.rangeInjection(v.Type(), func(index []int, name string, typ reflect.Type) error {
+		p, err := a.lookupProvider(name, typ)
+		if err != nil {
+			return err
+		}
+		val, err := p.get()
+		if err != nil {
+			return fmt.Errorf("flagx: failed to provide %s: %v", typ, err)
+		}
+		v.FieldByIndex(index).Set(val)
+		return nil
+	})
+}
+
+// rangeInjection calls fn for each injected field of the struct,
+// including the ones of the embedded structs.
+func (a *App) rangeInjection(t reflect.Type, fn func(index []int, name string, typ reflect.Type) error) error {
+	t = ameda.DereferenceType(t)
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	for i := 0; i < t.NumField(); i++ {
+		ft := t.Field(i)
+		tag, ok := ft.Tag.Lookup(tagNameFlag)
+		if !ok {
+			if ft.Anonymous && ft.Type.Kind() == reflect.Struct {
+				err := a.rangeInjection(ft.Type, func(index []int, name string, typ reflect.Type) error {
+					return fn(append([]int{i}, index...), name, typ)
+				})
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		name, ok := parseInjectTag(tag)
+		if !ok {
+			continue
+		}
+		if ft.PkgPath != "" {
+			return fmt.Errorf("flagx: can not inject unexported field %s", ft.Name)
+		}
+		if err := fn([]int{i}, name, ft.Type); err != nil {
+			return fmt.Errorf("%v, field=%s", err, ft.Name)
+		}
+	}
+	return nil
+}
+
+// parseInjectTag returns the service name of the injected field,
+// and reports whether the field is injected.
+func parseInjectTag(tag string) (string, bool) {
+	for _, key := range strings.Split(tag, ";") {
+		key = strings.TrimSpace(key)
+		if key == tagKeyInject {
+			return "", true
+		}
+		if name, ok := parseTagKey(key, tagKeyInject); ok {
+			return name, true
+		}
+	}
+	return "", false
+}
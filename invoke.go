@@ -0,0 +1,121 @@
+package flagx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Exec executes the command by the path under the app with the arguments,
+// such as c.Exec([]string{"build"}, []string{"-os=linux"}), and returns its status.
+// NOTE:
+//  the execution inherits the context values, the standard input, output and error,
+//  the environment and the executor scope, but not the hooks;
+//  the filters of the commands on the path are executed, except the ones of the
+//  commands that have applied them to the current execution if @skipAppliedFilters is true;
+//  only the filters and action of the target command parse the arguments,
+//  the commands before it reuse the filters created for them by the context chain,
+//  such as the ones of the app, and the others are parsed without arguments;
+//  it fails with StatusRecursion if the command is being executed by the context chain.
+func (c *Context) Exec(path []string, arguments []string, skipAppliedFilters ...bool) *Status {
+	return c.exec(path, arguments, len(skipAppliedFilters) > 0 && skipAppliedFilters[0])
+}
+
+// ExecCommand executes the command of the same app with the arguments, see Exec.
+func (c *Context) ExecCommand(cmd *Command, arguments []string, skipAppliedFilters ...bool) *Status {
+	if cmd.app != c.cmd.app {
+		return NewStatus(StatusBadArgs, "", fmt.Sprintf("command of another app: %q", cmd.PathString()))
+	}
+	return c.Exec(cmd.Path()[1:], arguments, skipAppliedFilters...)
+}
+
+func (c *Context) exec(path, arguments []string, skipAppliedFilters bool) *Status {
+	root := c.cmd.app.Command
+	ctxObj := &Context{
+		Context:    c.Context,
+		args:       append(append([]string{}, path...), arguments...),
+		cmdPath:    []string{root.cmdName},
+		cmd:        root,
+		execScope:  c.execScope,
+		flagOutput: c.flagOutput,
+		stdio:      c.stdio,
+		lookupEnv:  c.lookupEnv,
+		environ:    c.environ,
+		parent:     c,
+	}
+	if skipAppliedFilters {
+		ctxObj.skipped = make(map[*Command]bool)
+		for cmd := c.cmd; cmd != nil; cmd = cmd.parent {
+			ctxObj.skipped[cmd] = true
+		}
+	}
+	return root.app.catchStatus(ctxObj, func() {
+		handle := root.routePath(ctxObj, path, arguments)
+		for p := c; p != nil; p = p.parent {
+			if p.cmd == ctxObj.cmd {
+				ThrowStatus(
+					StatusRecursion,
+					"",
+					fmt.Sprintf("recursive execution: %q", strings.Join(ctxObj.execPath(), " -> ")),
+				)
+			}
+		}
+		root.app.withTimeout(ctxObj, handle)(ctxObj)
+	})
+}
+
+// execPath returns the command paths of the context chain from the outermost.
+func (c *Context) execPath() []string {
+	var paths []string
+	for p := c; p != nil; p = p.parent {
+		paths = append([]string{p.CmdPathString()}, paths...)
+	}
+	return paths
+}
+
+// appliedFilters returns the filters created for the command by the context chain.
+func (c *Context) appliedFilters(cmd *Command) ([]interface{}, bool) {
+	for p := c; p != nil; p = p.parent {
+		if filters, ok := p.applied[cmd]; ok {
+			return filters, true
+		}
+	}
+	return nil, false
+}
+
+// routePath routes the arguments from the command by the path under @c.
+// NOTE:
+//  the commands before the target one reuse the filters created for them by the
+//  context chain, or create them without arguments.
+func (c *Command) routePath(ctxObj *Context, path, arguments []string) ActionFunc {
+	var filters []interface{}
+	cmd := c
+	for _, name := range path {
+		if name == "" {
+			continue
+		}
+		cmd.lock.RLock()
+		if !ctxObj.skipped[cmd] {
+			cmdFilters, ok := ctxObj.parent.appliedFilters(cmd)
+			if !ok {
+				cmdFilters, _ = cmd.newFilters(ctxObj, nil)
+			}
+			filters = append(filters, cmdFilters...)
+		}
+		subCmd := cmd.lookupSubcommandLocked(name)
+		cmd.lock.RUnlock()
+		if subCmd == nil {
+			ctxObj.cmdPath = append(ctxObj.cmdPath, name)
+			ThrowStatus(
+				StatusNotFound,
+				"",
+				fmt.Sprintf("not found command action: %q", ctxObj.CmdPathString()),
+			)
+		}
+		ctxObj.cmdPath = append(ctxObj.cmdPath, subCmd.cmdName)
+		if subCmd.deprecated != "" {
+			c.app.warnOnce(ctxObj, subCmd.PathString(), "command %q is deprecated: %s", subCmd.PathString(), subCmd.deprecated)
+		}
+		cmd = subCmd
+	}
+	return cmd.routeFrom(ctxObj, filters, arguments)
+}
@@ -0,0 +1,151 @@
+package flagx
+
+import (
+	"context"
+	"io"
+	"os"
+	"strings"
+)
+
+type (
+	// LookupEnvFunc returns the value of the environment variable,
+	// and reports whether it is present, such as os.LookupEnv.
+	LookupEnvFunc func(key string) (string, bool)
+	// execIO the standard input, output and error of an execution.
+	execIO struct {
+		stdin  io.Reader
+		stdout io.Writer
+		stderr io.Writer
+	}
+)
+
+// SetIO sets the standard input, output and error of the executions,
+// which are used by the flagx messages and exposed by Context.Stdin, Stdout and Stderr.
+// NOTE:
+//  the nil ones default to os.Stdin, os.Stdout and os.Stderr;
+//  use WithIO to override them for an execution.
+func (a *App) SetIO(in io.Reader, out, err io.Writer) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.stdio = execIO{stdin: in, stdout: out, stderr: err}
+}
+
+// SetEnv sets the function to look up the environment variables of the executions,
+// which is used by the flags bound to the environment variables and exposed by Context.Getenv.
+// NOTE:
+//  defaults to os.LookupEnv;
+//  use WithEnv to override it for an execution;
+//  the lookup can not list the environment, so the plugins still run with os.Environ,
+//  use WithEnviron to set the environment of the plugins too.
+func (a *App) SetEnv(lookup LookupEnvFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.lookupEnv = lookup
+}
+
+// WithIO returns a copy of the parent context that overrides the standard input,
+// output and error of the execution, see App.SetIO.
+// NOTE:
+//  the nil ones are not overridden.
+func WithIO(parent context.Context, in io.Reader, out, err io.Writer) context.Context {
+	return context.WithValue(parent, ioContextKey, execIO{stdin: in, stdout: out, stderr: err})
+}
+
+// WithEnv returns a copy of the parent context that overrides the function
+// to look up the environment variables of the execution, see App.SetEnv.
+func WithEnv(parent context.Context, lookup LookupEnvFunc) context.Context {
+	return context.WithValue(parent, envContextKey, lookup)
+}
+
+// WithEnviron returns a copy of the parent context that overrides the environment
+// of the execution by the list of `key=value`, such as os.Environ.
+// NOTE:
+//  the environment is used by the lookup, see WithEnv, and passed to the plugins;
+//  the later one of the duplicate keys wins.
+func WithEnviron(parent context.Context, environ []string) context.Context {
+	environ = append([]string{}, environ...)
+	vars := make(map[string]string, len(environ))
+	for _, kv := range environ {
+		if i := strings.Index(kv, "="); i > 0 {
+			vars[kv[:i]] = kv[i+1:]
+		}
+	}
+	parent = context.WithValue(parent, environContextKey, environ)
+	return WithEnv(parent, func(key string) (string, bool) {
+		v, ok := vars[key]
+		return v, ok
+	})
+}
+
+// initIO sets the standard input, output, error and environment of the execution
+// from the app and the overrides in the context.
+func (c *Context) initIO(a *App) {
+	a.lock.RLock()
+	stdio, lookupEnv := a.stdio, a.lookupEnv
+	a.lock.RUnlock()
+	if v, ok := c.Value(ioContextKey).(execIO); ok {
+		if v.stdin != nil {
+			stdio.stdin = v.stdin
+		}
+		if v.stdout != nil {
+			stdio.stdout = v.stdout
+		}
+		if v.stderr != nil {
+			stdio.stderr = v.stderr
+		}
+	}
+	if v, ok := c.Value(envContextKey).(LookupEnvFunc); ok && v != nil {
+		lookupEnv = v
+	}
+	c.environ, _ = c.Value(environContextKey).([]string)
+	c.stdio, c.lookupEnv = stdio, lookupEnv
+}
+
+// Stdin returns the standard input of the execution.
+func (c *Context) Stdin() io.Reader {
+	if c.stdio.stdin == nil {
+		return os.Stdin
+	}
+	return c.stdio.stdin
+}
+
+// Stdout returns the standard output of the execution.
+func (c *Context) Stdout() io.Writer {
+	if c.stdio.stdout == nil {
+		return os.Stdout
+	}
+	return c.stdio.stdout
+}
+
+// Stderr returns the standard error of the execution.
+func (c *Context) Stderr() io.Writer {
+	if c.stdio.stderr == nil {
+		return os.Stderr
+	}
+	return c.stdio.stderr
+}
+
+// LookupEnv returns the value of the environment variable of the execution,
+// and reports whether it is present.
+func (c *Context) LookupEnv(key string) (string, bool) {
+	if c.lookupEnv == nil {
+		return os.LookupEnv(key)
+	}
+	return c.lookupEnv(key)
+}
+
+// Environ returns the copy of the environment of the execution, see WithEnviron,
+// which defaults to os.Environ.
+func (c *Context) Environ() []string {
+	if c.environ == nil {
+		return os.Environ()
+	}
+	return append([]string{}, c.environ...)
+}
+
+// Getenv returns the value of the environment variable of the execution,
+// returning empty if it is not present.
+func (c *Context) Getenv(key string) string {
+	v, _ := c.LookupEnv(key)
+	return v
+}
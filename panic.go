@@ -0,0 +1,138 @@
+package flagx
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+)
+
+// PanicPolicy how the execution handles an unexpected panic,
+// which is a panic with a value other than the status.
+type PanicPolicy int8
+
+const (
+	// PanicConvert converts the panic into a StatusPanic status,
+	// whose cause is *PanicError.
+	PanicConvert PanicPolicy = iota
+	// PanicRepanic panics again with the value.
+	PanicRepanic
+	// PanicHook calls the function set by App.SetPanicHandler.
+	PanicHook
+)
+
+type (
+	// PanicError the cause of the status converted from an unexpected panic.
+	PanicError struct {
+		Value interface{} // the value passed to panic
+		Stack []byte      // the stack of the goroutine when it panicked
+	}
+	// goroutinePanic the unexpected panic propagated from another goroutine,
+	// with the stack of that goroutine.
+	goroutinePanic struct {
+		PanicError
+	}
+	// PanicFunc is called with the unexpected panic, and returns the status of the execution.
+	// NOTE:
+	//  returning nil means the panic is handled.
+	PanicFunc func(c *Context, err *PanicError) *Status
+)
+
+// Error implements error interface.
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.Value)
+}
+
+// Unwrap returns the value passed to panic if it is an error.
+func (e *PanicError) Unwrap() error {
+	err, _ := e.Value.(error)
+	return err
+}
+
+// IsPanicStatus reports whether the status is converted from an unexpected panic.
+func IsPanicStatus(stat *Status) bool {
+	var panicErr *PanicError
+	return !stat.OK() && errors.As(stat.Cause(), &panicErr)
+}
+
+// SetPanicPolicy sets how the execution handles an unexpected panic
+// in the hooks, filters and actions.
+// NOTE:
+//  defaults to PanicConvert;
+//  PanicHook without the handler behaves as PanicConvert;
+//  when re-panicking, the OnError and After hooks are not called.
+func (a *App) SetPanicPolicy(policy PanicPolicy) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.panicPolicy = policy
+}
+
+// SetPanicHandler sets the function called with an unexpected panic,
+// and sets the panic policy to PanicHook.
+// NOTE:
+//  the status returned is handled as the failed status of the execution.
+func (a *App) SetPanicHandler(fn PanicFunc) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.panicPolicy = PanicHook
+	a.panicHandler = fn
+}
+
+// keepPanicStack re-panics the unexpected panic with the stack of the current goroutine,
+// so that the stack is kept when the panic is propagated to another goroutine.
+// NOTE:
+//  it must be deferred directly.
+func keepPanicStack() {
+	r := recover()
+	switch r.(type) {
+	case nil:
+		return
+	case *Status, Status, *goroutinePanic:
+		panic(r)
+	}
+	panic(&goroutinePanic{PanicError{Value: r, Stack: debug.Stack()}})
+}
+
+func (a *App) panicHandling() (PanicPolicy, PanicFunc) {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	return a.panicPolicy, a.panicHandler
+}
+
+// catchStatus calls the function and returns the status it panics with,
+// handling an unexpected panic by the panic policy.
+func (a *App) catchStatus(ctxObj *Context, fn func()) (stat *Status) {
+	defer func() {
+		r := recover()
+		switch v := r.(type) {
+		case nil:
+			stat = new(Status)
+			return
+		case *Status:
+			if v == nil {
+				v = new(Status)
+			}
+			stat = v
+			return
+		case Status:
+			stat = &v
+			return
+		}
+		var panicErr *PanicError
+		if p, ok := r.(*goroutinePanic); ok {
+			panicErr = &p.PanicError
+		} else {
+			panicErr = &PanicError{Value: r, Stack: debug.Stack()}
+		}
+		policy, handler := a.panicHandling()
+		switch {
+		case policy == PanicRepanic:
+			panic(panicErr.Value)
+		case policy == PanicHook && handler != nil:
+			stat = handler(ctxObj, panicErr)
+		default:
+			stat = NewStatus(StatusPanic, panicErr.Error(), panicErr).TagStack(2)
+		}
+	}()
+	fn()
+	return
+}
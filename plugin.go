@@ -0,0 +1,162 @@
+package flagx
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+)
+
+// SetPlugins sets whether an unknown subcommand executes the external plugin
+// named `<command path joined by '-'>-<subcommand>`, such as `mytool-foo` for `mytool foo`.
+// NOTE:
+//  the plugins are searched in @dirs, defaults to the directories of $PATH;
+//  the plugin is looked up when the subcommand is executed, so the ones installed later are found;
+//  the usage lists the plugins discovered when it or SetCmdName is called;
+//  the plugins run with the environment of Context.Environ, see WithEnviron.
+func (a *App) SetPlugins(enable bool, dirs ...string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.plugins = enable
+	a.pluginDirs = append([]string(nil), dirs...)
+	a.discoverPluginsLocked()
+	a.updateUsageLocked()
+}
+
+// pluginSearchDirs returns the directories to search for the plugins.
+func (a *App) pluginSearchDirs() []string {
+	if len(a.pluginDirs) > 0 {
+		return a.pluginDirs
+	}
+	return filepath.SplitList(os.Getenv("PATH"))
+}
+
+// discoverPluginsLocked discovers the plugins listed in the usage.
+func (a *App) discoverPluginsLocked() {
+	a.pluginPaths = nil
+	if a.plugins {
+		a.pluginPaths = discoverPlugins(a.pluginSearchDirs(), a.pluginPrefix())
+	}
+}
+
+// discoverPlugins returns the paths of the executables named with the prefix
+// in the directories by name, the first one found in the directory order wins.
+func discoverPlugins(dirs []string, prefix string) map[string]string {
+	paths := make(map[string]string)
+	for _, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, info := range infos {
+			if !isExecutable(info) {
+				continue
+			}
+			name := info.Name()
+			if runtime.GOOS == "windows" {
+				name = strings.TrimSuffix(name, filepath.Ext(name))
+			}
+			if !strings.HasPrefix(name, prefix) {
+				continue
+			}
+			if _, ok := paths[name]; !ok {
+				paths[name] = filepath.Join(dir, info.Name())
+			}
+		}
+	}
+	return paths
+}
+
+// findPlugin returns the path of the executable by name found first in the directories,
+// returning empty if none exists.
+func findPlugin(dirs []string, name string) string {
+	exts := []string{""}
+	if runtime.GOOS == "windows" {
+		pathExt := os.Getenv("PATHEXT")
+		if pathExt == "" {
+			pathExt = ".com;.exe;.bat;.cmd"
+		}
+		exts = strings.Split(strings.ToLower(pathExt), ";")
+	}
+	for _, dir := range dirs {
+		if dir == "" {
+			dir = "."
+		}
+		for _, ext := range exts {
+			path := filepath.Join(dir, name+ext)
+			if info, err := os.Stat(path); err == nil && isExecutable(info) {
+				return path
+			}
+		}
+	}
+	return ""
+}
+
+func isExecutable(info os.FileInfo) bool {
+	if !info.Mode().IsRegular() {
+		return false
+	}
+	return runtime.GOOS == "windows" || info.Mode()&0111 != 0
+}
+
+// pluginPrefix returns the name prefix of the plugins of the command.
+func (c *Command) pluginPrefix() string {
+	return strings.Join(c.Path(), "-") + "-"
+}
+
+// lookupPluginLocked returns the executable path of the plugin for the subcommand,
+// returning empty if none exists.
+func (c *Command) lookupPluginLocked(subCmdName string) string {
+	if subCmdName == "" || !c.app.plugins || strings.ContainsAny(subCmdName, `/\`) {
+		return ""
+	}
+	return findPlugin(c.app.pluginSearchDirs(), c.pluginPrefix()+subCmdName)
+}
+
+// pluginNamesLocked returns the subcommand names of the plugins of the command,
+// except the ones shadowed by the subcommands, and the plugins of the subcommands.
+func (c *Command) pluginNamesLocked() []string {
+	if c.action != nil {
+		return nil
+	}
+	var names []string
+	prefix := c.pluginPrefix()
+	for name := range c.app.pluginPaths {
+		subCmdName := strings.TrimPrefix(name, prefix)
+		if subCmdName == name || subCmdName == "" || c.lookupSubcommandLocked(subCmdName) != nil {
+			continue
+		}
+		if i := strings.Index(subCmdName, "-"); i > 0 && c.lookupSubcommandLocked(subCmdName[:i]) != nil {
+			continue
+		}
+		names = append(names, subCmdName)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// newPluginAction returns the action that executes the plugin with the arguments,
+// and the standard input, output and error of the context.
+// NOTE:
+//  panic a plugin-failed status when the plugin fails,
+//  whose cause is *exec.ExitError if it exits with a non-zero code.
+func newPluginAction(path string, arguments []string) ActionFunc {
+	return func(c *Context) {
+		cmd := exec.CommandContext(c, path, arguments...)
+		cmd.Stdin = c.Stdin()
+		cmd.Stdout = c.Stdout()
+		cmd.Stderr = c.Stderr()
+		cmd.Env = c.Environ()
+		err := cmd.Run()
+		if err != nil {
+			ThrowStatus(StatusPluginFailed, fmt.Sprintf("plugin %s: %v", filepath.Base(path), err), err)
+		}
+	}
+}
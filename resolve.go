@@ -0,0 +1,46 @@
+package flagx
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+)
+
+// Resolution the result of resolving a command line without executing it.
+type Resolution struct {
+	Command *Command      // the command reached, even if the resolution fails
+	CmdPath []string      // the command path reached
+	Filters []interface{} // the parsed filter objects from the outermost to the innermost
+	Action  interface{}   // the parsed action object, or the Action or ActionE function
+	Args    []string      // the arguments left by the action
+}
+
+// Resolve routes the arguments and parses the filters and action in the same way as Exec,
+// without calling the hooks, filters or action.
+// NOTE:
+//  the status fails if the command line is invalid, such as the not-found, parse and
+//  validation failures, even if the not-found action is set;
+//  the services are still injected into the parsed objects;
+//  the help and version flags are not handled.
+func (c *Command) Resolve(arguments []string, execScope ...Scope) (*Resolution, *Status) {
+	ctxObj := c.newContext(context.Background(), arguments, execScope, ioutil.Discard)
+	ctxObj.dryRun = true
+	stat := c.app.catchStatus(ctxObj, func() {
+		ctxObj.args, ctxObj.timeout = c.app.parseTimeoutOption(arguments)
+		c.route(ctxObj)
+		if ctxObj.action == nil {
+			ThrowStatus(
+				StatusNotFound,
+				"",
+				fmt.Sprintf("not found command action: %q", ctxObj.CmdPathString()),
+			)
+		}
+	})
+	return &Resolution{
+		Command: ctxObj.cmd,
+		CmdPath: ctxObj.cmdPath,
+		Filters: ctxObj.filters,
+		Action:  ctxObj.action,
+		Args:    ctxObj.nextArgs,
+	}, stat
+}
@@ -0,0 +1,122 @@
+package flagx
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+
+	"github.com/henrylee2cn/goutil"
+)
+
+// StatusRenderer renders the failed status of App.Run.
+// NOTE:
+//  @c holds the command reached before the failure.
+type StatusRenderer func(c *Context, stat *Status)
+
+// default exit codes
+const (
+	ExitCodeOK      = 0   // the status is OK
+	ExitCodeFailure = 1   // the status code has no exit code set
+	ExitCodeUsage   = 2   // the command line is invalid
+	ExitCodeSignal  = 130 // the execution is interrupted by the signals
+)
+
+// defaultExitCodes returns the default exit code mapping.
+func defaultExitCodes() map[int32]int {
+	return map[int32]int{
+		StatusBadArgs:        ExitCodeUsage,
+		StatusNotFound:       ExitCodeUsage,
+		StatusParseFailed:    ExitCodeUsage,
+		StatusValidateFailed: ExitCodeUsage,
+		StatusMismatchScope:  ExitCodeUsage,
+		StatusInterrupted:    ExitCodeSignal,
+	}
+}
+
+// Run executes the application with the command-line arguments,
+// renders the failed status and returns the exit code.
+// NOTE:
+//  @args contains the program name, such as os.Args.
+func (a *App) Run(args []string) int {
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	ctxObj, stat := a.run(context.Background(), args)
+	if !stat.OK() {
+		a.statusRenderer()(ctxObj, stat)
+	}
+	return a.ExitCode(stat)
+}
+
+// RunAndExit executes the application with os.Args and exits the program
+// with the exit code.
+func (a *App) RunAndExit() {
+	os.Exit(a.Run(os.Args))
+}
+
+func (a *App) run(ctx context.Context, arguments []string) (*Context, *Status) {
+	return a.Command.exec(ctx, arguments, nil, ioutil.Discard)
+}
+
+// SetExitCode sets the exit code of App.Run for the status code.
+// NOTE:
+//  By default, StatusBadArgs, StatusNotFound, StatusParseFailed,
+//  StatusValidateFailed and StatusMismatchScope exit with ExitCodeUsage,
+//  StatusInterrupted exits with ExitCodeSignal,
+//  the other failed statuses exit with ExitCodeFailure.
+func (a *App) SetExitCode(statusCode int32, exitCode int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.exitCodes[statusCode] = exitCode
+}
+
+// ExitCode returns the exit code of App.Run for the status.
+// NOTE:
+//  the exit code of the failed plugin is propagated.
+func (a *App) ExitCode(stat *Status) int {
+	if stat.OK() {
+		return ExitCodeOK
+	}
+	if exitErr, ok := stat.Cause().(*exec.ExitError); ok && exitErr.ExitCode() > 0 {
+		return exitErr.ExitCode()
+	}
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if code, ok := a.exitCodes[stat.Code()]; ok {
+		return code
+	}
+	return ExitCodeFailure
+}
+
+// SetStatusRenderer sets the function to render the failed status of App.Run.
+// NOTE:
+//  if fn is nil, the default renderer is used.
+func (a *App) SetStatusRenderer(fn StatusRenderer) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.renderer = fn
+}
+
+func (a *App) statusRenderer() StatusRenderer {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	if a.renderer == nil {
+		return defaultStatusRenderer
+	}
+	return a.renderer
+}
+
+// defaultStatusRenderer prints the status message to the standard error of the execution,
+// and the command usage for the invalid arguments.
+func defaultStatusRenderer(c *Context, stat *Status) {
+	w := c.Stderr()
+	fmt.Fprintf(w, "%s: %s\n", c.CmdPathString(), stat.Msg())
+	switch stat.Code() {
+	case StatusBadArgs, StatusParseFailed, StatusValidateFailed:
+		if usage := c.UsageText(); usage != "" {
+			fmt.Fprintf(w, "\nUSAGE:\n%s", goutil.Indent(usage, "  "))
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package flagx
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+// SetSignals sets whether the execution is canceled by SIGINT or SIGTERM.
+// NOTE:
+//  the first signal cancels the context of the execution, which can be checked
+//  by c.Done() in the filters and actions;
+//  a second signal within @grace after it aborts the execution with StatusInterrupted
+//  without waiting for the action to return, and @grace<=0 means no limit;
+//  the execution runs on a copy of the context, so when aborted, OnError and After
+//  are called with the context as it was before the routing, while the action may
+//  still be running on its copy.
+func (a *App) SetSignals(enable bool, grace time.Duration) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.signals = enable
+	a.signalGrace = grace
+}
+
+// notifySignals returns the context canceled by the first signal, the channel
+// receiving the interrupted status from the second signal, and the function
+// that stops the notification.
+// NOTE:
+//  the abort channel is nil when the signals are disabled.
+func (a *App) notifySignals(ctx context.Context) (context.Context, <-chan *Status, func()) {
+	a.lock.RLock()
+	enable, grace := a.signals, a.signalGrace
+	a.lock.RUnlock()
+	if !enable {
+		return ctx, nil, func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	sigCh := make(chan os.Signal, 2)
+	signal.Notify(sigCh, os.Interrupt, syscall.SIGTERM)
+	abort := make(chan *Status, 1)
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-sigCh:
+				cancel()
+			case <-done:
+				return
+			}
+			var timeout <-chan time.Time
+			if grace > 0 {
+				timeout = time.After(grace)
+			}
+			select {
+			case sig := <-sigCh:
+				abort <- NewStatus(StatusInterrupted, "interrupted by "+sig.String(), nil)
+				return
+			case <-timeout:
+			case <-done:
+				return
+			}
+		}
+	}()
+	return ctx, abort, func() {
+		signal.Stop(sigCh)
+		close(done)
+		cancel()
+	}
+}
+
+// execAbortable calls the function and returns its status,
+// or returns the status received from @abort without waiting for it to return,
+// and reports whether it is aborted.
+// NOTE:
+//  the function is called in the current goroutine if @abort is nil;
+//  the panic of the function is propagated to the current goroutine.
+func execAbortable(fn func() *Status, abort <-chan *Status) (*Status, bool) {
+	if abort == nil {
+		return fn(), false
+	}
+	type result struct {
+		stat     *Status
+		panicked bool
+		value    interface{}
+	}
+	resultCh := make(chan result, 1)
+	go func() {
+		r := result{panicked: true}
+		defer func() {
+			if r.panicked {
+				r.value = recover()
+			}
+			resultCh <- r
+		}()
+		r.stat = fn()
+		r.panicked = false
+	}()
+	select {
+	case r := <-resultCh:
+		if r.panicked {
+			panic(r.value)
+		}
+		return r.stat, false
+	case stat := <-abort:
+		return stat, true
+	}
+}
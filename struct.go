@@ -16,6 +16,11 @@ const (
 	tagKeyOmit        = "-"
 	tagKeyNameDefault = "def"
 	tagKeyNameUsage   = "usage"
+	tagKeyNameEnv     = "env"
+	tagKeyRequired    = "required"
+	tagKeyHidden      = "hidden"
+	// tag name of the deprecation message.
+	tagKeyNameDeprecated = "deprecated"
 	// tag name of the non-flag command-line arguments.
 	tagKeyNonFlag = "?"
 )
@@ -43,6 +48,13 @@ func (f *FlagSet) varFromStruct(v reflect.Value, structTypeIDs map[uintptr]struc
 		if tag == tagKeyOmit {
 			continue
 		}
+		if _, isInject := parseInjectTag(tag); isInject {
+			continue
+		}
+		if !ok && !ft.Anonymous {
+			// such as the prototype fields of the action
+			continue
+		}
 		if !ameda.InitPointer(fv) {
 			return fmt.Errorf("flagx: can not set field %s, type=%s", ft.Name, ft.Type.String())
 		}
@@ -69,19 +81,45 @@ func (f *FlagSet) varFromStruct(v reflect.Value, structTypeIDs map[uintptr]struc
 				return fmt.Errorf("flagx: not support field %s, type=%s, kind=%s", ft.Name, ft.Type.String(), kind)
 			}
 		}
-		keys := strings.SplitN(tag, ";", 3)
-		var def, usage string
+		var def, usage, env, deprecated string
+		var required, hidden bool
 		var names []string
-		for _, key := range keys {
-			key = strings.TrimSpace(key)
-			_def, ok := parseTagKey(key, tagKeyNameDefault)
-			if ok {
+		var inUsage bool
+		for _, piece := range strings.Split(tag, ";") {
+			key := strings.TrimSpace(piece)
+			if _def, ok := parseTagKey(key, tagKeyNameDefault); ok {
 				def = _def
+				inUsage = false
 				continue
 			}
-			_usage, ok := parseTagKey(key, tagKeyNameUsage)
-			if ok {
+			if _usage, ok := parseTagKey(key, tagKeyNameUsage); ok {
 				usage = _usage
+				inUsage = true
+				continue
+			}
+			if _env, ok := parseTagKey(key, tagKeyNameEnv); ok {
+				env = _env
+				inUsage = false
+				continue
+			}
+			if _deprecated, ok := parseTagKey(key, tagKeyNameDeprecated); ok {
+				deprecated = _deprecated
+				inUsage = false
+				continue
+			}
+			if key == tagKeyRequired {
+				required = true
+				inUsage = false
+				continue
+			}
+			if key == tagKeyHidden {
+				hidden = true
+				inUsage = false
+				continue
+			}
+			if inUsage && (len(names) > 0 || !isTagNames(key)) {
+				// the usage contains ';'
+				usage += ";" + piece
 				continue
 			}
 			names = parseTagNames(key)
@@ -93,6 +131,20 @@ func (f *FlagSet) varFromStruct(v reflect.Value, structTypeIDs map[uintptr]struc
 		if err != nil {
 			return err
 		}
+		for _, name := range names {
+			if required {
+				f.SetRequired(name)
+			}
+			if env != "" {
+				f.BindEnv(name, env)
+			}
+			if hidden {
+				f.SetHidden(name)
+			}
+			if deprecated != "" {
+				f.SetDeprecated(name, deprecated)
+			}
+		}
 	}
 	return nil
 }
@@ -267,6 +319,20 @@ func parseTagKey(key, keyName string) (string, bool) {
 	return strings.TrimSpace(v), true
 }
 
+// isTagNames reports whether the key can be a comma-separated list of names.
+func isTagNames(key string) bool {
+	if key == "" {
+		return false
+	}
+	for _, s := range strings.Split(key, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" || strings.ContainsAny(s, " \t=") {
+			return false
+		}
+	}
+	return true
+}
+
 func parseTagNames(key string) []string {
 	a := strings.Split(key, ",")
 	names := make([]string, 0, len(a))
@@ -0,0 +1,160 @@
+package flagx
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	// timeoutFlagName the name of the global timeout option, e.g. `--timeout=5m`.
+	timeoutFlagName = "timeout"
+	// defaultTimeoutGrace the default time waited for the action after the timeout.
+	defaultTimeoutGrace = time.Second
+)
+
+// SetTimeout sets the timeout of the command, which is inherited by its subcommands.
+// NOTE:
+//  the context of the filters and action is canceled when it expires, and if they
+//  do not return within the timeout grace after it, the execution fails with StatusTimeout;
+//  if d<=0, the timeout of the parent command is used.
+func (c *Command) SetTimeout(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.timeout = d
+	c.app.updateUsageLocked()
+}
+
+// Timeout returns the timeout of the command, inherited from the parent commands.
+func (c *Command) Timeout() time.Duration {
+	for r := c; r != nil; r = r.parent {
+		if r.timeout > 0 {
+			return r.timeout
+		}
+	}
+	return 0
+}
+
+// SetTimeoutOption sets whether the global `--timeout` option is accepted,
+// which overrides the timeout of the command executed.
+// NOTE:
+//  the option is removed from the arguments before `--` at any level,
+//  so that the commands can not define a flag named timeout.
+func (a *App) SetTimeoutOption(enable bool) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.timeoutOption = enable
+	a.updateUsageLocked()
+}
+
+// SetTimeoutGrace sets the time waited for the filters and action to return
+// after the timeout.
+// NOTE:
+//  defaults to 1s
+func (a *App) SetTimeoutGrace(grace time.Duration) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.timeoutGrace = grace
+}
+
+// parseTimeoutOption removes the global timeout option from the arguments,
+// and returns its value, returning 0 if it is disabled or absent.
+// NOTE:
+//  panic a bad-args status when the value is invalid.
+func (a *App) parseTimeoutOption(arguments []string) ([]string, time.Duration) {
+	a.lock.RLock()
+	enable := a.timeoutOption
+	a.lock.RUnlock()
+	if !enable {
+		return arguments, 0
+	}
+	var d time.Duration
+	args := make([]string, 0, len(arguments))
+	for i := 0; i < len(arguments); i++ {
+		s := arguments[i]
+		if s == "--" {
+			args = append(args, arguments[i:]...)
+			break
+		}
+		name, hasValue, ok := parseFlagToken(s)
+		if !ok || name != timeoutFlagName {
+			args = append(args, s)
+			continue
+		}
+		var value string
+		if hasValue {
+			value = s[strings.Index(s, "=")+1:]
+		} else if i+1 < len(arguments) {
+			i++
+			value = arguments[i]
+		}
+		var err error
+		d, err = time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			ThrowStatus(StatusBadArgs, "", fmt.Sprintf("invalid value %q for flag --%s", value, timeoutFlagName))
+		}
+	}
+	return args, d
+}
+
+// withTimeout returns the function that calls @fn with the timeout
+// of the command reached, returning @fn if there is no timeout.
+// NOTE:
+//  @fn is called in a new goroutine with a copy of the context if there is a timeout,
+//  and its panic is propagated with the stack of that goroutine;
+//  the context is updated by the copy when @fn returns, with the deadline and cancellation
+//  of the original one, so that the values set by @fn are kept;
+//  panic a timeout status if @fn does not return within the grace after the timeout.
+func (a *App) withTimeout(ctxObj *Context, fn ActionFunc) ActionFunc {
+	d := ctxObj.timeout
+	if d <= 0 {
+		d = ctxObj.cmd.Timeout()
+	}
+	if d <= 0 {
+		return fn
+	}
+	a.lock.RLock()
+	grace := a.timeoutGrace
+	a.lock.RUnlock()
+	return func(c *Context) {
+		parent := c.Context
+		ctx, cancel := context.WithTimeout(parent, d)
+		defer cancel()
+		cc := *c
+		cc.Context = ctx
+		aborted := false
+		defer func() {
+			if !aborted {
+				*c = cc
+				c.Context = valuesContext{Context: parent, values: cc.Context}
+			}
+		}()
+		abort := make(chan *Status, 1)
+		timer := time.AfterFunc(d+grace, func() {
+			abort <- NewStatus(StatusTimeout, fmt.Sprintf("timed out after %v", d), nil)
+		})
+		defer timer.Stop()
+		var stat *Status
+		stat, aborted = execAbortable(func() *Status {
+			defer keepPanicStack()
+			fn(&cc)
+			return nil
+		}, abort)
+		if aborted {
+			panic(stat)
+		}
+	}
+}
+
+// valuesContext the context with the deadline and cancellation of the embedded one,
+// and the values of another one.
+type valuesContext struct {
+	context.Context
+	values context.Context
+}
+
+// Value implements context.Context interface.
+func (c valuesContext) Value(key interface{}) interface{} {
+	return c.values.Value(key)
+}
@@ -0,0 +1,559 @@
+package flagx
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/henrylee2cn/goutil"
+)
+
+// UsageFormat the layout of the usage text.
+type UsageFormat int8
+
+const (
+	// ClassicUsage the tab-based layout of the standard flag package.
+	ClassicUsage UsageFormat = iota
+	// AlignedUsage aligns the flags in columns, wraps the text to the usage width
+	// and indents the nested commands.
+	AlignedUsage
+	// CompactUsage lists only the names and one-line descriptions of the subcommands,
+	// instead of the expanded tree.
+	CompactUsage
+)
+
+type (
+	// CommandUsage the usage data of a command, which is exposed to the usage templates.
+	CommandUsage struct {
+		Name        string          // the command name
+		Aliases     []string        // the alternative names of the command
+		Path        string          // the command path, such as `testapp b c`
+		Description string          // the command description
+		Deprecated  string          // the deprecation message, empty if it is not deprecated
+		HasAction   bool            // false if it is a group of subcommands
+		Default     bool            // whether it is the default subcommand of the parent
+		Timeout     time.Duration   // the timeout set on the command, 0 if it is inherited or absent
+		Flags       []*FlagUsage    // the flags of the filters and action
+		NonFlags    []*FlagUsage    // the non-flags of the filters and action
+		Group       string          // the group of the command
+		Subcommands []*CommandUsage // the subcommands visible in the usage
+		Groups      []*GroupUsage   // the subcommands by group in the group order
+		Plugins     []string        // the subcommand names of the external plugins
+		Width       int             // the width to which the usage is wrapped
+		cmd         *Command
+		all         []*FlagUsage // the flags and non-flags in definition order
+	}
+	// GroupUsage the subcommands in the same group.
+	GroupUsage struct {
+		Name        string          // the group name, empty for the ungrouped subcommands
+		Subcommands []*CommandUsage // the subcommands of the group
+	}
+	// FlagUsage the usage data of a flag or non-flag.
+	FlagUsage struct {
+		Name       string // the flag name, such as `id` or `?0`
+		Type       string // the value type, such as `int`, empty for the bool flag
+		Default    string // the default value, empty if it is the zero value
+		Usage      string // the usage without the back quotes
+		Required   bool   // whether the flag must be provided
+		Env        string // the environment variable bound to the flag
+		Deprecated string // the deprecation message, empty if it is not deprecated
+		Flag       *Flag  // the flag definition
+	}
+)
+
+const (
+	// defaultUsageWidth the usage width when $COLUMNS is not set.
+	defaultUsageWidth = 80
+	// minUsageWrapWidth the minimum width of the wrapped text.
+	minUsageWrapWidth = 20
+	usageIndent       = "  "
+	usageColumnGap    = "  "
+)
+
+// UsageFuncMap returns the functions available in the usage templates:
+//  wrap WIDTH TEXT: wraps the text to the width at spaces
+//  pad WIDTH TEXT: pads the text with spaces to the width
+//  indent PREFIX TEXT: adds the prefix to each line of the text
+func UsageFuncMap() template.FuncMap {
+	return template.FuncMap{
+		"wrap": func(width int, text string) string {
+			return strings.Join(wrapText(text, width), "\n")
+		},
+		"pad": func(width int, text string) string {
+			if n := width - len(text); n > 0 {
+				return text + strings.Repeat(" ", n)
+			}
+			return text
+		},
+		"indent": func(prefix, text string) string {
+			return goutil.Indent(text, prefix)
+		},
+	}
+}
+
+// SetUsageFormat sets the layout of the usage text.
+// NOTE:
+//  defaults to ClassicUsage
+func (a *App) SetUsageFormat(format UsageFormat) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.usageFormat = format
+	a.updateUsageLocked()
+}
+
+// SetUsageWidth sets the width to which AlignedUsage wraps the usage text.
+// NOTE:
+//  if width<=0, $COLUMNS is used, and defaults to 80
+func (a *App) SetUsageWidth(width int) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.usageWidth = width
+	a.updateUsageLocked()
+}
+
+// SetGroupOrder sets the order of the command groups in the usage,
+// the groups not in the order are listed after them by name.
+func (a *App) SetGroupOrder(groups ...string) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.groupOrder = groups
+	a.updateUsageLocked()
+}
+
+func (a *App) usageWidthLocked() int {
+	if a.usageWidth > 0 {
+		return a.usageWidth
+	}
+	if n, err := strconv.Atoi(os.Getenv("COLUMNS")); err == nil && n > 0 {
+		return n
+	}
+	return defaultUsageWidth
+}
+
+// SetUsageTemplate sets the template that renders the usage of the command
+// and its subcommands, whose data is the *CommandUsage of the command.
+// NOTE:
+//  see UsageFuncMap for the template functions;
+//  if tmpl is nil, the usage format of the app is used.
+func (c *Command) SetUsageTemplate(tmpl *template.Template) {
+	c.app.lock.Lock()
+	defer c.app.lock.Unlock()
+	c.usageTemplate = tmpl
+	c.app.updateUsageLocked()
+}
+
+// UsageData returns the structured usage data by the executor scope.
+// NOTE:
+//  if @scopes is empty, all commands are returned;
+//  returns nil if the command is not visible to the executor scope.
+func (c *Command) UsageData(execScope ...Scope) *CommandUsage {
+	c.app.lock.RLock()
+	defer c.app.lock.RUnlock()
+	if len(execScope) == 0 || c.app.scopeMatcherFunc == nil {
+		return c.usageDataLocked(nil)
+	}
+	return c.usageDataLocked(c.inScopeFunc(execScope[0]))
+}
+
+func (c *Command) usageDataLocked(visible func(*Command) bool) *CommandUsage {
+	if visible != nil && !visible(c) {
+		return nil
+	}
+	u := &CommandUsage{
+		Name:        c.cmdName,
+		Aliases:     c.aliases,
+		Path:        c.PathString(),
+		Description: c.description,
+		Deprecated:  c.deprecated,
+		HasAction:   c.action != nil,
+		Group:       c.group,
+		Timeout:     c.timeout,
+		Default:     c.parent != nil && c.parent.defaultSubcommand == c.cmdName,
+		Width:       c.app.usageWidthLocked(),
+		cmd:         c,
+	}
+	flagSets := make([]*FlagSet, 0, len(c.filters)+1)
+	for _, filter := range c.filters {
+		flagSets = append(flagSets, filter.flagSet)
+	}
+	if c.action != nil {
+		flagSets = append(flagSets, c.action.flagSet)
+	}
+	for _, flagSet := range flagSets {
+		flagSet := flagSet
+		flagSet.RangeAll(func(f *Flag) {
+			if flagSet.Hidden(f.Name) {
+				return
+			}
+			typ, usage := UnquoteUsage(f)
+			fu := &FlagUsage{
+				Name:       f.Name,
+				Type:       typ,
+				Usage:      usage,
+				Required:   flagSet.Required(f.Name),
+				Env:        flagSet.EnvKey(f.Name),
+				Deprecated: flagSet.Deprecated(f.Name),
+				Flag:       f,
+			}
+			if !isZeroValue(f, f.DefValue) {
+				fu.Default = f.DefValue
+			}
+			if IsNonFlag(f) {
+				u.NonFlags = append(u.NonFlags, fu)
+			} else {
+				u.Flags = append(u.Flags, fu)
+			}
+			u.all = append(u.all, fu)
+		})
+	}
+	if c.parent == nil && c.app.timeoutOption {
+		fu := timeoutFlagUsage(c.timeout)
+		u.Flags = append(u.Flags, fu)
+		u.all = append(u.all, fu)
+	}
+	for _, subCmd := range c.Subcommands() {
+		if subCmd.parentUsageVisible && !subCmd.hidden {
+			if sub := subCmd.usageDataLocked(visible); sub != nil {
+				u.Subcommands = append(u.Subcommands, sub)
+			}
+		}
+	}
+	u.Groups = c.app.groupSubcommandsLocked(u.Subcommands)
+	u.Plugins = c.pluginNamesLocked()
+	return u
+}
+
+// groupSubcommandsLocked groups the subcommands, the ungrouped ones come first,
+// then the groups in the group order, then the other groups by name.
+func (a *App) groupSubcommandsLocked(subcommands []*CommandUsage) []*GroupUsage {
+	var groups []*GroupUsage
+	m := make(map[string]*GroupUsage)
+	for _, sub := range subcommands {
+		g := m[sub.Group]
+		if g == nil {
+			g = &GroupUsage{Name: sub.Group}
+			m[sub.Group] = g
+			groups = append(groups, g)
+		}
+		g.Subcommands = append(g.Subcommands, sub)
+	}
+	rank := func(name string) int {
+		if name == "" {
+			return -1
+		}
+		for i, s := range a.groupOrder {
+			if s == name {
+				return i
+			}
+		}
+		return len(a.groupOrder)
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		ri, rj := rank(groups[i].Name), rank(groups[j].Name)
+		if ri != rj {
+			return ri < rj
+		}
+		return groups[i].Name < groups[j].Name
+	})
+	return groups
+}
+
+// Title returns the section header of the group, such as `Cluster commands:`.
+func (g *GroupUsage) Title() string {
+	if g.Name == "" {
+		return "Commands:"
+	}
+	return g.Name + " commands:"
+}
+
+// Title returns the command path with an ellipsis for the group of subcommands,
+// followed by the aliases and the default mark, such as `$testapp b ... (aliases: bb) (default)`.
+func (u *CommandUsage) Title() string {
+	s := "$" + u.Path
+	if !u.HasAction {
+		s += " ..."
+	}
+	if len(u.Aliases) > 0 {
+		s += " (aliases: " + strings.Join(u.Aliases, ", ") + ")"
+	}
+	if u.Default {
+		s += " (default)"
+	}
+	return s
+}
+
+// Detail returns the description with the timeout and the deprecation message.
+func (u *CommandUsage) Detail() string {
+	s := u.Description
+	if u.Timeout > 0 {
+		s += fmt.Sprintf(" (timeout %v)", u.Timeout)
+	}
+	if u.Deprecated != "" {
+		s += " (deprecated: " + u.Deprecated + ")"
+	}
+	return s
+}
+
+// timeoutFlagUsage returns the usage data of the global timeout option,
+// whose default value is the timeout of the app.
+func timeoutFlagUsage(timeout time.Duration) *FlagUsage {
+	usage := "the timeout of the command, which overrides its default timeout"
+	f := &Flag{Name: timeoutFlagName, Usage: usage, Value: newDurationValue(timeout, new(time.Duration))}
+	f.DefValue = f.Value.String()
+	fu := &FlagUsage{Name: timeoutFlagName, Type: "duration", Usage: usage, Flag: f}
+	if timeout > 0 {
+		fu.Default = f.DefValue
+	}
+	return fu
+}
+
+// Title returns the flag name with the value type, such as `-id int` or `?0 string`.
+func (f *FlagUsage) Title() string {
+	s := displayFlagName(f.Name)
+	if len(f.Type) > 0 {
+		s += " " + f.Type
+	}
+	return s
+}
+
+// Detail returns the usage with the default value, the requirement,
+// the environment variable and the deprecation message.
+func (f *FlagUsage) Detail() string {
+	s := f.Usage
+	if f.Default != "" {
+		if _, ok := f.Flag.Value.(*stringValue); ok {
+			// put quotes on the value
+			s += fmt.Sprintf(" (default %q)", f.Default)
+		} else {
+			s += fmt.Sprintf(" (default %v)", f.Default)
+		}
+	}
+	if f.Required {
+		s += " (required)"
+	}
+	if f.Env != "" {
+		s += " (env $" + f.Env + ")"
+	}
+	if f.Deprecated != "" {
+		s += " (deprecated: " + f.Deprecated + ")"
+	}
+	return s
+}
+
+// renderUsageLocked returns the usage text of the command and its subcommands,
+// each line of which starts with @indent.
+// NOTE:
+//  if @visible is not nil, only the commands it accepts are rendered.
+func (c *Command) renderUsageLocked(visible func(*Command) bool, indent string) string {
+	u := c.usageDataLocked(visible)
+	if u == nil {
+		return ""
+	}
+	var buf bytes.Buffer
+	writeUsage(&buf, u, c.app.usageFormat, indent)
+	return buf.String()
+}
+
+func writeUsage(buf *bytes.Buffer, u *CommandUsage, format UsageFormat, indent string) {
+	if tmpl := u.cmd.usageTemplate; tmpl != nil {
+		var b bytes.Buffer
+		err := tmpl.Execute(&b, u)
+		if err != nil {
+			panic(err)
+		}
+		buf.WriteString(goutil.Indent(b.String(), indent))
+		return
+	}
+	switch format {
+	case AlignedUsage:
+		writeAlignedUsage(buf, u, indent)
+	case CompactUsage:
+		writeCompactUsage(buf, u, indent)
+	default:
+		writeClassicUsage(buf, u, indent)
+	}
+}
+
+// writeClassicUsage writes the usage in the tab-based layout of the standard flag package.
+func writeClassicUsage(buf *bytes.Buffer, u *CommandUsage, indent string) {
+	flagIndent := indent
+	if u.cmd.parent != nil { // non-global command
+		fmt.Fprintf(buf, "%s%s\n%s%s%s\n", indent, u.Title(), indent, usageIndent, u.Detail())
+		flagIndent += usageIndent
+	}
+	for _, f := range u.all {
+		s := flagIndent + f.Title()
+		// Boolean flags of one ASCII letter are so common we
+		// treat them specially, putting their usage on the same line.
+		if len(f.Type) == 0 && len(f.Name) == 1 {
+			s += "\t"
+		} else {
+			// Two spaces before the tab triggers good alignment
+			// for both 4- and 8-space tab stops.
+			s += "\n" + flagIndent + "  \t"
+		}
+		s += strings.Replace(f.Detail(), "\n", "\n"+flagIndent+"  \t", -1)
+		buf.WriteString(s)
+		buf.WriteString("\n")
+	}
+	writeGroups(buf, u, ClassicUsage, indent)
+}
+
+// writeAlignedUsage writes the usage with the flags aligned in columns,
+// the text wrapped to the width, and the subcommands indented.
+func writeAlignedUsage(buf *bytes.Buffer, u *CommandUsage, indent string) {
+	flagIndent := indent
+	if u.cmd.parent != nil { // non-global command
+		fmt.Fprintf(buf, "%s%s\n", indent, u.Title())
+		flagIndent += usageIndent
+		for _, line := range wrapText(u.Detail(), u.Width-len(flagIndent)) {
+			fmt.Fprintf(buf, "%s%s\n", flagIndent, line)
+		}
+	}
+	writeAlignedFlags(buf, u.all, flagIndent, u.Width)
+	writeGroups(buf, u, AlignedUsage, flagIndent)
+}
+
+// writeGroups writes the subcommands with a section header for each group,
+// the ungrouped ones have no header.
+func writeGroups(buf *bytes.Buffer, u *CommandUsage, format UsageFormat, indent string) {
+	for _, g := range u.Groups {
+		if g.Name != "" {
+			fmt.Fprintf(buf, "%s%s\n", indent, g.Title())
+		}
+		for _, sub := range g.Subcommands {
+			writeUsage(buf, sub, format, indent)
+		}
+	}
+	if len(u.Plugins) > 0 {
+		fmt.Fprintf(buf, "%sPlugin commands:\n", indent)
+		for _, name := range u.Plugins {
+			fmt.Fprintf(buf, "%s$%s %s\n", indent, u.Path, name)
+		}
+	}
+}
+
+// writeCompactUsage writes the flags of the command, and only the names and
+// one-line descriptions of its subcommands by group.
+func writeCompactUsage(buf *bytes.Buffer, u *CommandUsage, indent string) {
+	flagIndent := indent
+	if u.cmd.parent != nil { // non-global command
+		fmt.Fprintf(buf, "%s%s\n%s%s%s\n", indent, u.Title(), indent, usageIndent, firstLine(u.Detail()))
+		flagIndent += usageIndent
+	}
+	writeAlignedFlags(buf, u.all, flagIndent, u.Width)
+	var column int
+	for _, sub := range u.Subcommands {
+		if n := len(sub.Name); n > column {
+			column = n
+		}
+	}
+	for _, name := range u.Plugins {
+		if n := len(name); n > column {
+			column = n
+		}
+	}
+	for _, g := range u.Groups {
+		fmt.Fprintf(buf, "%s%s\n", flagIndent, g.Title())
+		for _, sub := range g.Subcommands {
+			fmt.Fprintf(buf, "%s%s%s%s%s\n", flagIndent, usageIndent, sub.Name,
+				strings.Repeat(" ", column-len(sub.Name)+len(usageColumnGap)), firstLine(sub.Detail()))
+		}
+	}
+	if len(u.Plugins) > 0 {
+		fmt.Fprintf(buf, "%sPlugin commands:\n", flagIndent)
+		for _, name := range u.Plugins {
+			fmt.Fprintf(buf, "%s%s%s\n", flagIndent, usageIndent, name)
+		}
+	}
+}
+
+// firstLine returns the first line of the text.
+func firstLine(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
+
+// writeAlignedFlags writes the flags with the titles aligned in a column,
+// and the details wrapped to the width.
+func writeAlignedFlags(buf *bytes.Buffer, flags []*FlagUsage, indent string, width int) {
+	if len(flags) == 0 {
+		return
+	}
+	maxColumn := (width - len(indent)) / 3
+	var column int
+	for _, f := range flags {
+		if n := len(f.Title()); n > column && n <= maxColumn {
+			column = n
+		}
+	}
+	wrapWidth := width - len(indent) - column - len(usageColumnGap)
+	blank := strings.Repeat(" ", column+len(usageColumnGap))
+	for _, f := range flags {
+		title := f.Title()
+		lines := wrapText(f.Detail(), wrapWidth)
+		buf.WriteString(indent)
+		buf.WriteString(title)
+		if len(lines) == 0 {
+			buf.WriteString("\n")
+			continue
+		}
+		if len(title) > column {
+			// the title is too long, so the detail starts on the next line
+			buf.WriteString("\n")
+			buf.WriteString(indent)
+			buf.WriteString(blank)
+		} else {
+			buf.WriteString(strings.Repeat(" ", column-len(title)))
+			buf.WriteString(usageColumnGap)
+		}
+		for i, line := range lines {
+			if i > 0 {
+				buf.WriteString("\n")
+				buf.WriteString(indent)
+				buf.WriteString(blank)
+			}
+			buf.WriteString(line)
+		}
+		buf.WriteString("\n")
+	}
+}
+
+// wrapText splits the text into lines no longer than the width,
+// breaking at spaces and keeping the explicit line breaks.
+// NOTE:
+//  a word longer than the width is not broken.
+func wrapText(text string, width int) []string {
+	if width < minUsageWrapWidth {
+		width = minUsageWrapWidth
+	}
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		words := strings.Fields(paragraph)
+		if len(words) == 0 {
+			lines = append(lines, "")
+			continue
+		}
+		line := words[0]
+		for _, word := range words[1:] {
+			if len(line)+1+len(word) > width {
+				lines = append(lines, line)
+				line = word
+			} else {
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	if len(lines) == 1 && lines[0] == "" {
+		return nil
+	}
+	return lines
+}
@@ -0,0 +1,125 @@
+package flagx
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// versionCmdName the name of the built-in version command, e.g. `app version`.
+const versionCmdName = "version"
+
+// BuildInfo the version information embedded in the binary by the go command.
+type BuildInfo struct {
+	Version  string    // the main module version, such as v1.2.3
+	Revision string    // the vcs.revision setting
+	Time     time.Time // the vcs.time setting
+	Modified bool      // the vcs.modified setting, true if the working tree was dirty
+}
+
+// ReadBuildInfo returns the build information embedded in the running binary.
+// NOTE:
+//  the fields are empty if the binary is built without module or vcs support;
+//  the vcs fields are only set by go1.18 or later.
+func ReadBuildInfo() BuildInfo {
+	var info BuildInfo
+	bi, ok := debug.ReadBuildInfo()
+	if !ok {
+		return info
+	}
+	if v := bi.Main.Version; v != "(devel)" {
+		info.Version = v
+	}
+	readVCSSettings(bi, &info)
+	return info
+}
+
+// defaultVersionTemplate is the text template for the version command.
+var defaultVersionTemplate = template.Must(template.New("version").
+	Parse(`{{.Name}} v{{.Version}}{{with .Revision}} ({{.}}{{if $.Modified}}, modified{{end}}){{end}}{{if not .Compiled.IsZero}}
+compiled: {{.Compiled.Format "2006-01-02 15:04:05 MST"}}{{end}}{{if len .Authors}}
+author{{with $length := len .Authors}}{{if ne 1 $length}}s{{end}}{{end}}: {{range $index, $author := .Authors}}{{if $index}}, {{end}}{{$author}}{{end}}{{end}}
+`))
+
+// SetVersionTemplate sets the template of the version printed by
+// `--version` or the `version` command.
+func (a *App) SetVersionTemplate(tmpl *template.Template) {
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	a.versionTemplate = tmpl
+}
+
+// VersionText returns the version text printed by `--version` or the `version` command.
+func (a *App) VersionText() string {
+	a.lock.RLock()
+	defer a.lock.RUnlock()
+	name := a.appName
+	if name == "" {
+		name = a.cmdName
+	}
+	data := map[string]interface{}{
+		"AppName":  a.appName,
+		"CmdName":  a.cmdName,
+		"Name":     name,
+		"Version":  a.version,
+		"Revision": a.buildInfo.Revision,
+		"Modified": a.buildInfo.Modified,
+		"Compiled": a.compiled,
+		"Authors":  a.authors,
+	}
+	var buf bytes.Buffer
+	err := a.versionTemplate.Execute(&buf, data)
+	if err != nil {
+		panic(err)
+	}
+	return buf.String()
+}
+
+// execVersion prints the version if the arguments request it.
+func (c *Command) execVersion(ctxObj *Context, arguments []string) bool {
+	if c != c.app.Command || !c.findVersion(arguments) {
+		return false
+	}
+	fmt.Fprint(c.app.helpWriter(ctxObj), c.app.VersionText())
+	return true
+}
+
+// findVersion reports whether the arguments of the top level contain
+// the `--version` flag or start with the `version` command.
+func (c *Command) findVersion(arguments []string) bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for i := 0; i < len(arguments); i++ {
+		s := arguments[i]
+		name, hasValue, ok := parseFlagToken(s)
+		if !ok {
+			return s == versionCmdName && c.lookupSubcommandLocked(versionCmdName) == nil
+		}
+		f := c.lookupFormalFlag(name)
+		if f == nil && name == versionCmdName && !hasValue {
+			return true
+		}
+		if !hasValue && i+1 < len(arguments) && takesValue(f, arguments[i+1]) {
+			i++
+		}
+	}
+	return false
+}
+
+// parseFlagToken returns the flag name of the argument,
+// and reports whether the argument is a flag.
+// NOTE:
+//  "--" is not a flag.
+func parseFlagToken(s string) (name string, hasValue bool, ok bool) {
+	if len(s) < 2 || s[0] != '-' || s == "--" {
+		return "", false, false
+	}
+	name = strings.TrimLeft(s, "-")
+	if i := strings.Index(name, "="); i >= 0 {
+		return name[:i], true, true
+	}
+	return name, false, true
+}
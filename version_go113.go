@@ -0,0 +1,11 @@
+//go:build !go1.18
+// +build !go1.18
+
+package flagx
+
+import (
+	"runtime/debug"
+)
+
+// readVCSSettings does nothing, since the vcs settings are embedded since go1.18.
+func readVCSSettings(*debug.BuildInfo, *BuildInfo) {}
@@ -0,0 +1,23 @@
+//go:build go1.18
+// +build go1.18
+
+package flagx
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// readVCSSettings sets the vcs fields of the build information.
+func readVCSSettings(bi *debug.BuildInfo, info *BuildInfo) {
+	for _, s := range bi.Settings {
+		switch s.Key {
+		case "vcs.revision":
+			info.Revision = s.Value
+		case "vcs.time":
+			info.Time, _ = time.Parse(time.RFC3339, s.Value)
+		case "vcs.modified":
+			info.Modified = s.Value == "true"
+		}
+	}
+}